@@ -0,0 +1,70 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func modulesString(modules []bool) string {
+	var b strings.Builder
+	for _, m := range modules {
+		if m {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+func TestEncodeCode128(t *testing.T) {
+	const (
+		startB = "11010010000"
+		stop   = "1100011101011"
+	)
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		// A is value 33; the check value is (104 + 1*33) % 103 = 34
+		{"single character", "A", startB + "10100011000" + "10001011000" + stop},
+		// AB: values 33 and 34; the check value is (104 + 33 + 2*34) % 103 = 102
+		{"weighted checksum", "AB", startB + "10100011000" + "10001011000" + "11110101110" + stop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modules, err := EncodeCode128(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := modulesString(modules); got != tt.want {
+				t.Errorf("EncodeCode128(%q) =\n%s\nwant\n%s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeCode128Length(t *testing.T) {
+	for _, text := range []string{"RWX-ABCDEFGH2", " ", "~", "Voucher 42!"} {
+		modules, err := EncodeCode128(text)
+		if err != nil {
+			t.Fatalf("EncodeCode128(%q): %v", text, err)
+		}
+		// start, data and check symbols are 11 modules each, the stop symbol 13
+		if want := 11*(len(text)+2) + 13; len(modules) != want {
+			t.Errorf("EncodeCode128(%q) has %d modules, want %d", text, len(modules), want)
+		}
+		if !modules[0] || !modules[len(modules)-1] {
+			t.Errorf("EncodeCode128(%q) must start and end with a bar", text)
+		}
+	}
+}
+
+func TestEncodeCode128Rejects(t *testing.T) {
+	for _, text := range []string{"", "tab\there", "café", "line\n"} {
+		if _, err := EncodeCode128(text); err == nil {
+			t.Errorf("EncodeCode128(%q) should fail", text)
+		}
+	}
+}
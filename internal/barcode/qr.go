@@ -0,0 +1,427 @@
+package barcode
+
+import (
+	"errors"
+)
+
+// ErrTooLong is returned when the data does not fit the supported symbol sizes
+var ErrTooLong = errors.New("data too long to encode")
+
+// QR error correction level M, versions 1-10: total codewords, EC codewords
+// per block and number of blocks, indexed by version.
+var (
+	qrTotalCodewords = [...]int{0, 26, 44, 70, 100, 134, 172, 196, 242, 292, 346}
+	qrECCPerBlock    = [...]int{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26}
+	qrNumBlocks      = [...]int{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5}
+	qrAlignment      = [...][]int{nil, {}, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34}, {6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50}}
+)
+
+const (
+	qrMaxVersion = 10
+	// format bits of error correction level M
+	qrLevelM = 0
+)
+
+// QR is an encoded QR code symbol. Modules[y][x] is true for a dark module.
+type QR struct {
+	Size    int
+	Modules [][]bool
+	isFunc  [][]bool
+}
+
+// EncodeQR encodes data in byte mode at error correction level M using the
+// smallest version that fits.
+func EncodeQR(data []byte) (*QR, error) {
+	version := 0
+	for v := 1; v <= qrMaxVersion; v++ {
+		if qrDataCapacityBits(v) >= qrPayloadBits(v, len(data)) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	codewords := qrDataCodewords(version, data)
+	all := qrAddECCAndInterleave(version, codewords)
+
+	size := version*4 + 17
+	q := &QR{Size: size, Modules: grid(size), isFunc: grid(size)}
+	q.drawFunctionPatterns(version)
+	q.drawCodewords(all)
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			bestMask, bestPenalty = mask, p
+		}
+		q.applyMask(mask) // masking is its own inverse
+	}
+	q.applyMask(bestMask)
+	q.drawFormatBits(bestMask)
+	return q, nil
+}
+
+func grid(size int) [][]bool {
+	g := make([][]bool, size)
+	for i := range g {
+		g[i] = make([]bool, size)
+	}
+	return g
+}
+
+func qrCharCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func qrPayloadBits(version, n int) int {
+	return 4 + qrCharCountBits(version) + 8*n
+}
+
+func qrDataCapacityBits(version int) int {
+	return (qrTotalCodewords[version] - qrECCPerBlock[version]*qrNumBlocks[version]) * 8
+}
+
+// qrDataCodewords builds the mode indicator, length, data, terminator and padding
+func qrDataCodewords(version int, data []byte) []byte {
+	var bits []bool
+	appendBits := func(val, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, (val>>i)&1 == 1)
+		}
+	}
+	appendBits(0x4, 4) // byte mode
+	appendBits(len(data), qrCharCountBits(version))
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	capacity := qrDataCapacityBits(version)
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	appendBits(0, terminator)
+	appendBits(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		appendBits(pad, 8)
+	}
+
+	out := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			out[i>>3] |= 1 << (7 - uint(i&7))
+		}
+	}
+	return out
+}
+
+// qrAddECCAndInterleave splits data into blocks, appends Reed-Solomon EC
+// codewords to each and interleaves them into the final codeword sequence
+func qrAddECCAndInterleave(version int, data []byte) []byte {
+	numBlocks := qrNumBlocks[version]
+	eccLen := qrECCPerBlock[version]
+	raw := qrTotalCodewords[version]
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+
+	divisor := rsDivisor(eccLen)
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i < numBlocks; i++ {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		dat := append([]byte{}, data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(dat, divisor)
+		if i < numShort {
+			dat = append(dat, 0)
+		}
+		blocks[i] = append(dat, ecc...)
+	}
+
+	var result []byte
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			// Short blocks carry a placeholder byte at the end of their data
+			if i != shortLen-eccLen || j >= numShort {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+func (q *QR) setFunc(x, y int, dark bool) {
+	q.Modules[y][x] = dark
+	q.isFunc[y][x] = true
+}
+
+func (q *QR) drawFunctionPatterns(version int) {
+	for i := 0; i < q.Size; i++ {
+		q.setFunc(6, i, i%2 == 0)
+		q.setFunc(i, 6, i%2 == 0)
+	}
+	q.drawFinder(3, 3)
+	q.drawFinder(q.Size-4, 3)
+	q.drawFinder(3, q.Size-4)
+
+	pos := qrAlignment[version]
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			q.drawAlignment(pos[i], pos[j])
+		}
+	}
+	q.drawFormatBits(0) // reserve the area; real bits are drawn after masking
+	q.drawVersion(version)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func (q *QR) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			dist := abs(dx)
+			if abs(dy) > dist {
+				dist = abs(dy)
+			}
+			xx, yy := x+dx, y+dy
+			if xx >= 0 && xx < q.Size && yy >= 0 && yy < q.Size {
+				q.setFunc(xx, yy, dist != 2 && dist != 4)
+			}
+		}
+	}
+}
+
+func (q *QR) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			dist := abs(dx)
+			if abs(dy) > dist {
+				dist = abs(dy)
+			}
+			q.setFunc(x+dx, y+dy, dist != 1)
+		}
+	}
+}
+
+func (q *QR) drawFormatBits(mask int) {
+	data := qrLevelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunc(8, i, bit(i))
+	}
+	q.setFunc(8, 7, bit(6))
+	q.setFunc(8, 8, bit(7))
+	q.setFunc(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunc(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.setFunc(q.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunc(8, q.Size-15+i, bit(i))
+	}
+	q.setFunc(8, q.Size-8, true) // always-dark module
+}
+
+func (q *QR) drawVersion(version int) {
+	if version < 7 {
+		return
+	}
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 == 1
+		a, b := q.Size-11+i%3, i/3
+		q.setFunc(a, b, dark)
+		q.setFunc(b, a, dark)
+	}
+}
+
+// drawCodewords places the codeword bits in the zigzag order of the spec
+func (q *QR) drawCodewords(data []byte) {
+	i := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				upward := (right+1)&2 == 0
+				y := vert
+				if upward {
+					y = q.Size - 1 - vert
+				}
+				if !q.isFunc[y][x] && i < len(data)*8 {
+					q.Modules[y][x] = (data[i>>3]>>(7-uint(i&7)))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (q *QR) applyMask(mask int) {
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.isFunc[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.Modules[y][x] = !q.Modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol with the four mask evaluation rules of the spec
+func (q *QR) penalty() int {
+	n := q.Size
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return q.Modules[x][y]
+		}
+		return q.Modules[y][x]
+	}
+	finderA := []bool{true, false, true, true, true, false, true, false, false, false, false}
+	finderB := []bool{false, false, false, false, true, false, true, true, true, false, true}
+
+	score := 0
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < n; y++ {
+			run := 1
+			for x := 1; x < n; x++ {
+				if at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					if run == 5 {
+						score += 3
+					} else if run > 5 {
+						score++
+					}
+				} else {
+					run = 1
+				}
+			}
+			for x := 0; x+len(finderA) <= n; x++ {
+				matchA, matchB := true, true
+				for k := range finderA {
+					v := at(x+k, y, vertical)
+					matchA = matchA && v == finderA[k]
+					matchB = matchB && v == finderB[k]
+				}
+				if matchA || matchB {
+					score += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if q.Modules[y][x] {
+				dark++
+			}
+			if x < n-1 && y < n-1 {
+				c := q.Modules[y][x]
+				if c == q.Modules[y][x+1] && c == q.Modules[y+1][x] && c == q.Modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+	total := n * n
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	if k > 0 {
+		score += k * 10
+	}
+	return score
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the given degree
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the EC codewords of data for the divisor
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
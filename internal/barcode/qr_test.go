@@ -0,0 +1,180 @@
+package barcode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncodeQRVersion(t *testing.T) {
+	// Byte-mode capacities at level M are 14, 26, 42, ... 213 bytes for versions 1-10
+	tests := []struct {
+		length   int
+		wantSize int
+	}{
+		{0, 21},
+		{14, 21},
+		{15, 25},
+		{26, 25},
+		{27, 29},
+		{122, 45},
+		{123, 49},
+		{213, 57},
+	}
+	for _, tt := range tests {
+		q, err := EncodeQR(bytes.Repeat([]byte("a"), tt.length))
+		if err != nil {
+			t.Fatalf("EncodeQR(%d bytes): %v", tt.length, err)
+		}
+		if q.Size != tt.wantSize {
+			t.Errorf("EncodeQR(%d bytes) has size %d, want %d", tt.length, q.Size, tt.wantSize)
+		}
+	}
+	if _, err := EncodeQR(bytes.Repeat([]byte("a"), 214)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("EncodeQR(214 bytes) error = %v, want ErrTooLong", err)
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// HELLO WORLD as a version 1-M symbol, from the worked example of the spec
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+// readFormat returns the level and mask read from the copy of the format bits
+// beside the top-left finder, checking it against the second copy.
+func readFormat(t *testing.T, q *QR) (level, mask int) {
+	t.Helper()
+	var first, second int
+	bit := func(v *int, i int, dark bool) {
+		if dark {
+			*v |= 1 << uint(i)
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		bit(&first, i, q.Modules[i][8])
+	}
+	bit(&first, 6, q.Modules[7][8])
+	bit(&first, 7, q.Modules[8][8])
+	bit(&first, 8, q.Modules[8][7])
+	for i := 9; i < 15; i++ {
+		bit(&first, i, q.Modules[8][14-i])
+	}
+	for i := 0; i < 8; i++ {
+		bit(&second, i, q.Modules[8][q.Size-1-i])
+	}
+	for i := 8; i < 15; i++ {
+		bit(&second, i, q.Modules[q.Size-15+i][8])
+	}
+	if first != second {
+		t.Fatalf("format copies differ: %015b and %015b", first, second)
+	}
+	data := (first ^ 0x5412) >> 10
+	return data >> 3, data & 7
+}
+
+// readCodewords unmasks the symbol and reads back its codewords in placement order
+func readCodewords(q *QR, mask int) []byte {
+	q.applyMask(mask)
+	defer q.applyMask(mask)
+	var out []byte
+	var cur byte
+	n := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vert
+				}
+				if q.isFunc[y][x] {
+					continue
+				}
+				cur <<= 1
+				if q.Modules[y][x] {
+					cur |= 1
+				}
+				if n++; n%8 == 0 {
+					out = append(out, cur)
+					cur = 0
+				}
+			}
+		}
+	}
+	return out
+}
+
+func TestEncodeQRRoundTrip(t *testing.T) {
+	// Versions 1-3 use a single block, so codewords are read back without de-interleaving
+	payloads := []string{"", "RWX-ABCDEFGH2", strings.Repeat("x", 40)}
+	for _, payload := range payloads {
+		q, err := EncodeQR([]byte(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		version := (q.Size - 17) / 4
+		level, mask := readFormat(t, q)
+		if level != qrLevelM {
+			t.Errorf("%q: level bits %d, want M", payload, level)
+		}
+		if !q.Modules[q.Size-8][8] {
+			t.Errorf("%q: dark module missing", payload)
+		}
+
+		codewords := readCodewords(q, mask)
+		dataLen := qrTotalCodewords[version] - qrECCPerBlock[version]
+		data, ecc := codewords[:dataLen], codewords[dataLen:qrTotalCodewords[version]]
+		if got := rsRemainder(data, rsDivisor(len(ecc))); !bytes.Equal(got, ecc) {
+			t.Errorf("%q: error correction codewords do not match the data", payload)
+		}
+		if mode := data[0] >> 4; mode != 0x4 {
+			t.Fatalf("%q: mode %x, want byte mode", payload, mode)
+		}
+		length := int(data[0]&0x0F)<<4 | int(data[1]>>4)
+		decoded := make([]byte, length)
+		for i := range decoded {
+			decoded[i] = data[1+i]<<4 | data[2+i]>>4
+		}
+		if string(decoded) != payload {
+			t.Errorf("decoded %q, want %q", decoded, payload)
+		}
+	}
+}
+
+func TestEncodeQRFunctionPatterns(t *testing.T) {
+	q, err := EncodeQR([]byte("https://example.com/coupon?code=RWX-ABCDEFGH2&sig=0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	finder := []string{
+		"1111111",
+		"1000001",
+		"1011101",
+		"1011101",
+		"1011101",
+		"1000001",
+		"1111111",
+	}
+	for _, corner := range [][2]int{{0, 0}, {q.Size - 7, 0}, {0, q.Size - 7}} {
+		for dy, row := range finder {
+			for dx, c := range row {
+				if q.Modules[corner[1]+dy][corner[0]+dx] != (c == '1') {
+					t.Fatalf("finder at %v is wrong at (%d, %d)", corner, dx, dy)
+				}
+			}
+		}
+	}
+	for i := 8; i < q.Size-8; i++ {
+		if q.Modules[6][i] != (i%2 == 0) || q.Modules[i][6] != (i%2 == 0) {
+			t.Fatalf("timing pattern is wrong at %d", i)
+		}
+	}
+}
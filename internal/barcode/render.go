@@ -0,0 +1,96 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// quietZone is the light margin, in modules, around a QR code
+const quietZone = 4
+
+// PNG renders the QR code with scale pixels per module
+func (q *QR) PNG(scale int) ([]byte, error) {
+	dim := (q.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, dim, dim))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.Modules[y][x] {
+				fillRect(img, (x+quietZone)*scale, (y+quietZone)*scale, scale, scale)
+			}
+		}
+	}
+	return encodePNG(img)
+}
+
+// SVG renders the QR code as a scalable image measured in modules
+func (q *QR) SVG() []byte {
+	dim := q.Size + 2*quietZone
+	var path strings.Builder
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.Modules[y][x] {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`, dim, dim, path.String()))
+}
+
+// LinearPNG renders a 1D barcode with scale pixels per module and the given bar height
+func LinearPNG(modules []bool, scale, height int) ([]byte, error) {
+	margin := 10 * scale
+	img := image.NewGray(image.Rect(0, 0, len(modules)*scale+2*margin, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	for i, bar := range modules {
+		if bar {
+			fillRect(img, margin+i*scale, 0, scale, height)
+		}
+	}
+	return encodePNG(img)
+}
+
+// LinearSVG renders a 1D barcode as a scalable image measured in modules
+func LinearSVG(modules []bool, height int) []byte {
+	const margin = 10
+	var path strings.Builder
+	for i := 0; i < len(modules); {
+		if !modules[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < len(modules) && modules[i] {
+			i++
+		}
+		fmt.Fprintf(&path, "M%d 0h%dv%dh-%dz", start+margin, i-start, height, i-start)
+	}
+	width := len(modules) + 2*margin
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`, width, height, path.String()))
+}
+
+func fillRect(img *image.Gray, x0, y0, w, h int) {
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			img.SetGray(x, y, color.Gray{Y: 0})
+		}
+	}
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
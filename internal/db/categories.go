@@ -0,0 +1,62 @@
+package db
+
+import (
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"strings"
+)
+
+// backfillCategories turns the free-form category strings of existing rewards
+// into managed categories. Names differing only in case or punctuation share
+// one category, named after the first spelling seen.
+func backfillCategories() error {
+	var names []string
+	err := DB.Model(&models.Reward{}).
+		Where("category_id IS NULL AND category <> ''").
+		Order("category").
+		Distinct().Pluck("category", &names).Error
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		slug := utils.Slugify(name)
+		if slug == "" {
+			continue
+		}
+		category := models.Category{Name: strings.TrimSpace(name), Slug: slug}
+		if err := DB.Where("slug = ?", slug).FirstOrCreate(&category).Error; err != nil {
+			return err
+		}
+		err := DB.Model(&models.Reward{}).
+			Where("category_id IS NULL AND category = ?", name).
+			Updates(map[string]interface{}{"category_id": category.ID, "category": category.Name}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupCategoryKeys adds foreign keys from rewards and subcategories to their
+// category, so a category still in use can never be deleted. References to
+// categories that no longer exist are cleared first.
+func setupCategoryKeys() error {
+	statements := []string{
+		"UPDATE rewards SET category_id = NULL WHERE category_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM categories c WHERE c.id = rewards.category_id)",
+		"UPDATE categories SET parent_id = NULL WHERE parent_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM categories p WHERE p.id = categories.parent_id)",
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_rewards_category') THEN
+				ALTER TABLE rewards ADD CONSTRAINT fk_rewards_category FOREIGN KEY (category_id) REFERENCES categories (id);
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_categories_parent') THEN
+				ALTER TABLE categories ADD CONSTRAINT fk_categories_parent FOREIGN KEY (parent_id) REFERENCES categories (id);
+			END IF;
+		END $$`,
+	}
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
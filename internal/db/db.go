@@ -1,9 +1,11 @@
 package db
 
 import (
+	"authapi/internal/ledger"
 	"authapi/internal/models"
 	"log"
 	"os"
+	"time"
     "authapi/internal/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -13,43 +15,129 @@ var DB *gorm.DB
 
 func Connect() {
 	dsn := os.Getenv("DB_DSN")
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	DB = database
-	DB.AutoMigrate(&models.User{}, &models.Reward{}, &models.Transaction{})
+	if err := dedupeCouponCodes(); err != nil {
+		log.Fatal("Failed to de-duplicate coupon codes:", err)
+	}
+	DB.AutoMigrate(&models.User{}, &models.Reward{}, &models.Transaction{}, &models.RefreshToken{}, &models.APIKey{}, &models.LedgerEntry{}, &models.IdempotencyKey{}, &models.EarnRule{}, &models.EarnEvent{}, &models.EarnAward{}, &models.PointLot{}, &models.PointLotDraw{}, &models.Referral{}, &models.VoucherCode{}, &models.Category{}, &models.PartnerApplication{})
+	if err := setupApplicationIndex(); err != nil {
+		log.Fatal("Failed to index pending partner applications:", err)
+	}
+	if err := ledger.Backfill(DB); err != nil {
+		log.Fatal("Failed to backfill points ledger:", err)
+	}
+	if err := ledger.BackfillLots(DB); err != nil {
+		log.Fatal("Failed to backfill point lots:", err)
+	}
+	if err := backfillRewardReviews(); err != nil {
+		log.Fatal("Failed to queue partner rewards for review:", err)
+	}
+	setupSearch()
 	SeedData()
+	// Runs after seeding so the seeded rewards are categorised as well
+	if err := backfillCategories(); err != nil {
+		log.Fatal("Failed to backfill categories:", err)
+	}
+	if err := setupCategoryKeys(); err != nil {
+		log.Fatal("Failed to add category foreign keys:", err)
+	}
+}
+
+// backfillRewardReviews queues partner rewards that were never reviewed for
+// approval. They predate reviews and were marked approved by the column default.
+func backfillRewardReviews() error {
+	result := DB.Model(&models.Reward{}).
+		Where("approval_status = ? AND reviewed_at IS NULL AND created_by_id IN (?)", models.RewardApproved,
+			DB.Unscoped().Model(&models.User{}).Select("id").Where("role = ?", "partner")).
+		Updates(map[string]interface{}{"approval_status": models.RewardSubmitted, "submitted_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Queued %d partner rewards for review\n", result.RowsAffected)
+	}
+	return nil
+}
+
+// setupApplicationIndex allows one pending partner application per email, so
+// concurrent applications cannot both get past the duplicate check. Earlier
+// duplicates keep the oldest application pending and reject the rest.
+func setupApplicationIndex() error {
+	result := DB.Exec(`UPDATE partner_applications a SET status = ?, rejection_reason = ?
+		FROM partner_applications first
+		WHERE lower(first.email) = lower(a.email) AND first.id < a.id AND first.status = ? AND a.status = ?`,
+		models.ApplicationRejected, "Duplicate application", models.ApplicationPending, models.ApplicationPending)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Rejected %d duplicate pending partner applications\n", result.RowsAffected)
+	}
+	return DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_partner_applications_pending_email
+		ON partner_applications (lower(email)) WHERE status = 'pending'`).Error
+}
+
+// dedupeCouponCodes makes legacy coupon codes unique so the unique index on
+// transactions.coupon_code can be created. The oldest transaction keeps its
+// code; later copies get their transaction ID appended.
+func dedupeCouponCodes() error {
+	if !DB.Migrator().HasColumn(&models.Transaction{}, "coupon_code") {
+		return nil
+	}
+	result := DB.Exec(`UPDATE transactions t SET coupon_code = t.coupon_code || '-' || t.id
+		FROM transactions first
+		WHERE first.coupon_code = t.coupon_code AND first.id < t.id AND t.coupon_code <> ''`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Renamed %d duplicate coupon codes\n", result.RowsAffected)
+	}
+	return nil
 }
 func SeedData() {
 	adminpassword, err := utils.HashingPassword("admin123")
 	if err != nil {
 		log.Fatal("Failed to hash admin password:", err)
 	}
-	DB.Save(&models.User{
+	seedUser(models.User{
 		Email:      "admin@rewardx.com",
 		Username:   "Admin",
 		Password:   adminpassword,
 		Role:       "admin",
-		Points:      1000,
 	    IsVerified:  true,
-	})
+	}, 1000)
 	partnerpassword, err := utils.HashingPassword("partner123")
 	if err != nil {
 		log.Fatal("Failed to hash partner password:", err)
 	}
-	DB.Save(&models.User{
+	seedUser(models.User{
 		
 		Email:    "partner@brand.com",
 		Username: "Partner",
 		Password: partnerpassword,
 		Role:     "partner",
-		Points:   500,
 		IsVerified:  true,
-	})
+	}, 500)
 
 	DB.Save(&models.Reward{Name: "Amazon Gift Card", Category: "Shopping", Cost: 100, Stock: 50})
 	DB.Save(&models.Reward{Name: "Flipkart Voucher", Category: "Shopping", Cost: 80, Stock: 30})
 	DB.Save(&models.Reward{Name: "Movie Tickets", Category: "Entertainment", Cost: 50, Stock: 20})
 
-}
\ No newline at end of file
+}
+
+// seedUser creates the user once and credits their opening balance through the ledger
+func seedUser(u models.User, points int) {
+	u.ReferralCode = utils.GenerateReferralCode()
+	DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("email = ?", u.Email).FirstOrCreate(&u)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return ledger.Credit(tx, u.ID, points, ledger.ReasonOpeningBalance, nil)
+	})
+}
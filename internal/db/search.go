@@ -0,0 +1,43 @@
+package db
+
+import (
+	"log"
+)
+
+// Search capabilities detected at startup. FullTextSearch needs a generated
+// tsvector column (Postgres 12+), TrigramSearch the pg_trgm extension.
+var (
+	FullTextSearch bool
+	TrigramSearch  bool
+)
+
+// setupSearch adds the weighted search vector over rewards (name > category >
+// description) with its GIN index, plus trigram indexes for fuzzy matching.
+// Missing privileges or an old server only disable the affected search mode.
+func setupSearch() {
+	err := DB.Exec(`ALTER TABLE rewards ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(category, '')), 'B') ||
+			setweight(to_tsvector('english', coalesce(description, '')), 'C')
+		) STORED`).Error
+	if err == nil {
+		err = DB.Exec("CREATE INDEX IF NOT EXISTS idx_rewards_search_vector ON rewards USING GIN (search_vector)").Error
+	}
+	if err != nil {
+		log.Println("Full-text search disabled:", err)
+	}
+	FullTextSearch = err == nil
+
+	err = DB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error
+	if err == nil {
+		err = DB.Exec("CREATE INDEX IF NOT EXISTS idx_rewards_name_trgm ON rewards USING GIN (name gin_trgm_ops)").Error
+	}
+	if err == nil {
+		err = DB.Exec("CREATE INDEX IF NOT EXISTS idx_rewards_category_trgm ON rewards USING GIN (category gin_trgm_ops)").Error
+	}
+	if err != nil {
+		log.Println("Fuzzy search disabled:", err)
+	}
+	TrigramSearch = err == nil
+}
@@ -0,0 +1,130 @@
+package earning
+
+import (
+	"authapi/internal/models"
+	"errors"
+	"math"
+	"strings"
+)
+
+// Rule types understood by the engine
+const (
+	RuleFixed      = "fixed"
+	RulePercent    = "percent"
+	RuleMultiplier = "multiplier"
+)
+
+// Event is a qualifying action reported by a partner
+type Event struct {
+	Action    string
+	Amount    float64
+	Category  string
+	PartnerID uint
+}
+
+// Award is the points a single rule contributes to an event
+type Award struct {
+	RuleID uint `json:"rule_id"`
+	Points int  `json:"points"`
+}
+
+// ValidateRule checks that a rule is complete for its type. Every rule belongs
+// to one partner and needs a daily cap, so no partner can mint unlimited points.
+func ValidateRule(rule models.EarnRule) error {
+	if rule.Action == "" {
+		return errors.New("action is required")
+	}
+	if rule.PartnerID == 0 {
+		return errors.New("partner_id is required")
+	}
+	if rule.DailyCap <= 0 {
+		return errors.New("daily_cap must be positive")
+	}
+	switch rule.Type {
+	case RuleFixed:
+		if rule.Points <= 0 {
+			return errors.New("points must be positive for fixed rules")
+		}
+	case RulePercent:
+		if rule.Percent <= 0 {
+			return errors.New("percent must be positive for percent rules")
+		}
+	case RuleMultiplier:
+		if rule.Multiplier <= 1 {
+			return errors.New("multiplier must be greater than 1 for multiplier rules")
+		}
+	default:
+		return errors.New("type must be fixed, percent or multiplier")
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to the event. Rules without a
+// partner or a daily cap predate those requirements and never apply.
+func matches(rule models.EarnRule, event Event) bool {
+	if !rule.Active || rule.Action != event.Action || rule.DailyCap <= 0 {
+		return false
+	}
+	if rule.PartnerID == 0 || rule.PartnerID != event.PartnerID {
+		return false
+	}
+	return rule.Category == "" || strings.EqualFold(rule.Category, event.Category)
+}
+
+// Evaluate applies every matching rule to the event. Fixed and percent rules
+// award base points scaled by tierMultiplier. Each multiplier rule then awards
+// a bonus of (Multiplier - 1) times those base points. Every award, bonuses
+// included, is limited by its rule's daily cap given the points the rule has
+// already awarded today in earnedToday.
+func Evaluate(rules []models.EarnRule, event Event, earnedToday map[uint]int, tierMultiplier float64) []Award {
+	if tierMultiplier <= 0 {
+		tierMultiplier = 1
+	}
+	capped := func(rule models.EarnRule, points int) int {
+		remaining := rule.DailyCap - earnedToday[rule.ID]
+		if points > remaining {
+			points = remaining
+		}
+		return points
+	}
+
+	var awards []Award
+	base := 0
+	for _, rule := range rules {
+		if !matches(rule, event) {
+			continue
+		}
+		var points float64
+		switch rule.Type {
+		case RuleFixed:
+			points = float64(rule.Points)
+		case RulePercent:
+			points = event.Amount * rule.Percent / 100
+		default:
+			continue
+		}
+		if awarded := capped(rule, int(math.Floor(points*tierMultiplier))); awarded > 0 {
+			awards = append(awards, Award{RuleID: rule.ID, Points: awarded})
+			base += awarded
+		}
+	}
+	for _, rule := range rules {
+		if rule.Type != RuleMultiplier || !matches(rule, event) {
+			continue
+		}
+		bonus := int(math.Floor(float64(base) * (rule.Multiplier - 1)))
+		if awarded := capped(rule, bonus); awarded > 0 {
+			awards = append(awards, Award{RuleID: rule.ID, Points: awarded})
+		}
+	}
+	return awards
+}
+
+// Total sums the points of all awards
+func Total(awards []Award) int {
+	total := 0
+	for _, a := range awards {
+		total += a.Points
+	}
+	return total
+}
@@ -0,0 +1,120 @@
+package earning
+
+import (
+	"authapi/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestValidateRule(t *testing.T) {
+	valid := models.EarnRule{Action: "purchase", Type: RuleFixed, Points: 10, PartnerID: 7, DailyCap: 100}
+	tests := []struct {
+		name    string
+		edit    func(r *models.EarnRule)
+		wantErr bool
+	}{
+		{"valid fixed", func(r *models.EarnRule) {}, false},
+		{"missing action", func(r *models.EarnRule) { r.Action = "" }, true},
+		{"global rule", func(r *models.EarnRule) { r.PartnerID = 0 }, true},
+		{"uncapped", func(r *models.EarnRule) { r.DailyCap = 0 }, true},
+		{"negative cap", func(r *models.EarnRule) { r.DailyCap = -1 }, true},
+		{"fixed without points", func(r *models.EarnRule) { r.Points = 0 }, true},
+		{"percent", func(r *models.EarnRule) { r.Type = RulePercent; r.Percent = 5 }, false},
+		{"percent without percent", func(r *models.EarnRule) { r.Type = RulePercent }, true},
+		{"multiplier", func(r *models.EarnRule) { r.Type = RuleMultiplier; r.Multiplier = 2 }, false},
+		{"multiplier of one", func(r *models.EarnRule) { r.Type = RuleMultiplier; r.Multiplier = 1 }, true},
+		{"unknown type", func(r *models.EarnRule) { r.Type = "bonus" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := valid
+			tt.edit(&rule)
+			if err := ValidateRule(rule); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	fixed := models.EarnRule{ID: 1, Action: "purchase", Type: RuleFixed, Points: 10, PartnerID: 7, DailyCap: 100, Active: true}
+	percent := models.EarnRule{ID: 2, Action: "purchase", Type: RulePercent, Percent: 5, PartnerID: 7, DailyCap: 1000, Active: true}
+	double := models.EarnRule{ID: 3, Action: "purchase", Type: RuleMultiplier, Multiplier: 2, PartnerID: 7, DailyCap: 50, Active: true}
+	event := Event{Action: "purchase", Amount: 200, Category: "Food", PartnerID: 7}
+
+	tests := []struct {
+		name        string
+		rules       []models.EarnRule
+		event       Event
+		earnedToday map[uint]int
+		tier        float64
+		want        []Award
+	}{
+		{
+			name:  "fixed and percent",
+			rules: []models.EarnRule{fixed, percent},
+			event: event,
+			tier:  1,
+			want:  []Award{{RuleID: 1, Points: 10}, {RuleID: 2, Points: 10}},
+		},
+		{
+			name:  "tier multiplier scales base points",
+			rules: []models.EarnRule{fixed},
+			event: event,
+			tier:  1.5,
+			want:  []Award{{RuleID: 1, Points: 15}},
+		},
+		{
+			name:  "multiplier adds a capped bonus",
+			rules: []models.EarnRule{fixed, percent, double},
+			event: Event{Action: "purchase", Amount: 2000, PartnerID: 7},
+			tier:  1,
+			want:  []Award{{RuleID: 1, Points: 10}, {RuleID: 2, Points: 100}, {RuleID: 3, Points: 50}},
+		},
+		{
+			name:        "daily cap limits what is left today",
+			rules:       []models.EarnRule{fixed},
+			event:       event,
+			earnedToday: map[uint]int{1: 95},
+			tier:        1,
+			want:        []Award{{RuleID: 1, Points: 5}},
+		},
+		{
+			name:        "exhausted cap awards nothing",
+			rules:       []models.EarnRule{fixed, double},
+			event:       event,
+			earnedToday: map[uint]int{1: 100, 3: 50},
+			tier:        1,
+			want:        nil,
+		},
+		{
+			name:  "other partner's rules do not apply",
+			rules: []models.EarnRule{fixed},
+			event: Event{Action: "purchase", PartnerID: 8},
+			tier:  1,
+			want:  nil,
+		},
+		{
+			name:  "uncapped legacy rule does not apply",
+			rules: []models.EarnRule{{ID: 4, Action: "purchase", Type: RulePercent, Percent: 50, PartnerID: 7, Active: true}},
+			event: event,
+			tier:  1,
+			want:  nil,
+		},
+		{
+			name:  "category must match",
+			rules: []models.EarnRule{{ID: 5, Action: "purchase", Type: RuleFixed, Points: 10, Category: "travel", PartnerID: 7, DailyCap: 10, Active: true}},
+			event: event,
+			tier:  1,
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Evaluate(tt.rules, tt.event, tt.earnedToday, tt.tier)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
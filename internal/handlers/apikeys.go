@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"errors"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var errKeyRevoked = errors.New("api key already revoked")
+
+// newAPIKey creates a key record for the partner and returns it with the plaintext key
+func newAPIKey(tx *gorm.DB, userID uint, name string) (models.APIKey, string, error) {
+	key, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	apiKey := models.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: utils.HashToken(key),
+	}
+	if err := tx.Create(&apiKey).Error; err != nil {
+		return models.APIKey{}, "", err
+	}
+	return apiKey, key, nil
+}
+
+// findPartnerAPIKey loads a live API key owned by the logged-in partner
+func findPartnerAPIKey(c *fiber.Ctx) (models.APIKey, error) {
+	userID := uint(c.Locals("user_id").(float64))
+	var apiKey models.APIKey
+	keyID, err := c.ParamsInt("id")
+	if err != nil {
+		return apiKey, err
+	}
+	err = db.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).First(&apiKey).Error
+	return apiKey, err
+}
+
+// CreateAPIKey issues a new API key for the logged-in partner. The key is only shown once.
+func CreateAPIKey(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	var input struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	apiKey, key, err := newAPIKey(db.DB, userID, input.Name)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create API key"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"api_key": key, "key": apiKey})
+}
+
+// ListAPIKeys retrieves all API keys of the logged-in partner
+func ListAPIKeys(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	var keys []models.APIKey
+	db.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&keys)
+	return c.JSON(keys)
+}
+
+// revokeAPIKey stamps the key as revoked, keeping the time of an earlier revocation
+func revokeAPIKey(tx *gorm.DB, apiKey models.APIKey) error {
+	result := tx.Model(&apiKey).Where("revoked_at IS NULL").Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errKeyRevoked
+	}
+	return nil
+}
+
+// RotateAPIKey revokes an API key and issues a replacement with the same name
+func RotateAPIKey(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	old, err := findPartnerAPIKey(c)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "API key not found"})
+	}
+	var apiKey models.APIKey
+	var key string
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := revokeAPIKey(tx, old); err != nil {
+			return err
+		}
+		var err error
+		apiKey, key, err = newAPIKey(tx, userID, old.Name)
+		return err
+	})
+	if errors.Is(err, errKeyRevoked) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "API key already revoked"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not rotate API key"})
+	}
+	return c.JSON(fiber.Map{"api_key": key, "key": apiKey})
+}
+
+// RevokeAPIKey permanently disables an API key of the logged-in partner
+func RevokeAPIKey(c *fiber.Ctx) error {
+	apiKey, err := findPartnerAPIKey(c)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "API key not found"})
+	}
+	err = revokeAPIKey(db.DB, apiKey)
+	if errors.Is(err, errKeyRevoked) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "API key already revoked"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not revoke API key"})
+	}
+	return c.JSON(fiber.Map{"message": "API key revoked"})
+}
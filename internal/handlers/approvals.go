@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errNotSubmittable = errors.New("only draft or rejected rewards can be submitted")
+	errNotSubmitted   = errors.New("reward is not awaiting review")
+)
+
+// rewardContentChanged reports whether an edit touched anything an admin reviewed.
+// Stock and the active switch can change without a new review.
+func rewardContentChanged(before, after models.Reward) bool {
+	return before.Name != after.Name ||
+		before.Category != after.Category ||
+		derefID(before.CategoryID) != derefID(after.CategoryID) ||
+		before.Cost != after.Cost ||
+		before.Discount != after.Discount ||
+		before.CampaignName != after.CampaignName ||
+		before.Description != after.Description ||
+		before.MinTier != after.MinTier ||
+		!before.StartDate.Equal(after.StartDate) ||
+		!before.EndDate.Equal(after.EndDate) ||
+		before.AutoExpireAfterRedemption != after.AutoExpireAfterRedemption
+}
+
+// notifyRewardReview emails the partner the outcome of a review
+func notifyRewardReview(reward models.Reward) {
+	go func() {
+		var partner models.User
+		if err := db.DB.First(&partner, reward.CreatedByID).Error; err != nil {
+			return
+		}
+		approved := reward.ApprovalStatus == models.RewardApproved
+		if err := utils.SendRewardReviewEmail(partner.Email, reward.Name, approved, reward.RejectionReason); err != nil {
+			log.Println("Could not send reward review email:", err)
+		}
+	}()
+}
+
+// PartnerSubmitReward sends a draft or rejected reward to the admins for review
+func PartnerSubmitReward(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	rewardID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid reward ID"})
+	}
+	var reward models.Reward
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, rewardID).Error; err != nil {
+			return errRewardNotFound
+		}
+		if reward.CreatedByID != userID {
+			return errNotRewardOwner
+		}
+		if reward.ApprovalStatus != models.RewardDraft && reward.ApprovalStatus != models.RewardRejected {
+			return errNotSubmittable
+		}
+		now := time.Now()
+		reward.ApprovalStatus = models.RewardSubmitted
+		reward.SubmittedAt = &now
+		return tx.Model(&reward).Updates(map[string]interface{}{
+			"approval_status": reward.ApprovalStatus,
+			"submitted_at":    now,
+		}).Error
+	})
+	switch {
+	case errors.Is(err, errRewardNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Reward not found"})
+	case errors.Is(err, errNotRewardOwner):
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this reward"})
+	case errors.Is(err, errNotSubmittable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": errNotSubmittable.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not submit reward"})
+	}
+	return c.JSON(fiber.Map{"message": "Reward submitted for review", "reward": reward})
+}
+
+// AdminListRewardsForReview lists rewards by approval status, submitted ones by default
+func AdminListRewardsForReview(c *fiber.Ctx) error {
+	status := c.Query("status", models.RewardSubmitted)
+	return rewardPage(c, db.DB.Where("approval_status = ?", status))
+}
+
+// reviewReward records an admin's decision on a submitted reward
+func reviewReward(c *fiber.Ctx, status, reason string) error {
+	adminID := uint(c.Locals("user_id").(float64))
+	rewardID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid reward ID"})
+	}
+	var reward models.Reward
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, rewardID).Error; err != nil {
+			return errRewardNotFound
+		}
+		if reward.ApprovalStatus != models.RewardSubmitted {
+			return errNotSubmitted
+		}
+		now := time.Now()
+		reward.ApprovalStatus = status
+		reward.RejectionReason = reason
+		reward.ReviewedByID = adminID
+		reward.ReviewedAt = &now
+		return tx.Model(&reward).Updates(map[string]interface{}{
+			"approval_status":  status,
+			"rejection_reason": reason,
+			"reviewed_by_id":   adminID,
+			"reviewed_at":      now,
+		}).Error
+	})
+	switch {
+	case errors.Is(err, errRewardNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Reward not found"})
+	case errors.Is(err, errNotSubmitted):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": errNotSubmitted.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not review reward"})
+	}
+	notifyRewardReview(reward)
+	return c.JSON(fiber.Map{"message": "Reward " + status, "reward": reward})
+}
+
+// AdminApproveReward publishes a submitted reward to the catalog
+func AdminApproveReward(c *fiber.Ctx) error {
+	return reviewReward(c, models.RewardApproved, "")
+}
+
+// AdminRejectReward sends a submitted reward back to its partner with a reason
+func AdminRejectReward(c *fiber.Ctx) error {
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A rejection reason is required"})
+	}
+	return reviewReward(c, models.RewardRejected, input.Reason)
+}
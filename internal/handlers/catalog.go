@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/tiers"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// rewardSorts are the ?sort values accepted by reward lists
+var rewardSorts = map[string]pageSort{
+	"newest":    {column: "id", desc: true},
+	"oldest":    {column: "id"},
+	"cost_asc":  {column: "cost", kind: sortInt},
+	"cost_desc": {column: "cost", kind: sortInt, desc: true},
+	"name":      {column: "name", kind: sortString},
+}
+
+// transactionSorts are the ?sort values accepted by transaction lists
+var transactionSorts = map[string]pageSort{
+	"newest": {column: "created_at", kind: sortTime, desc: true},
+	"oldest": {column: "created_at", kind: sortTime},
+}
+
+// likePattern turns free text into an ILIKE pattern that matches it literally anywhere
+func likePattern(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+	return "%" + escaped + "%"
+}
+
+// catalogRewards starts a reward query limited to what the caller may see in
+// the catalog: available rewards within their tier, minus once-per-user
+// rewards they already redeemed
+func catalogRewards(tx *gorm.DB, c *fiber.Ctx) *gorm.DB {
+	query := availableRewards(tx, time.Now())
+	if userID, ok := c.Locals("user_id").(float64); ok {
+		var user models.User
+		if err := db.DB.First(&user, uint(userID)).Error; err == nil {
+			return query.Where("min_tier = '' OR min_tier IN ?", tiers.EligibleNames(user.Tier)).
+				Where("NOT (auto_expire_after_redemption AND id IN (?))", redeemedBy(tx, user.ID))
+		}
+	}
+	return query.Where("min_tier = ''")
+}
+
+// filterRewards applies the catalog query parameters: category (ID, slug or
+// name, including its subcategories), min_cost, max_cost, in_stock, partner
+// and campaign
+func filterRewards(c *fiber.Ctx, query *gorm.DB) (*gorm.DB, error) {
+	if ref := strings.TrimSpace(c.Query("category")); ref != "" {
+		category, err := findCategory(db.DB, ref)
+		if err != nil {
+			return nil, errUnknownCategory
+		}
+		ids, err := categoryWithDescendants(db.DB, category.ID)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("category_id IN ?", ids)
+	}
+	if raw := c.Query("min_cost"); raw != "" {
+		minCost, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, errors.New("min_cost must be a number")
+		}
+		query = query.Where("cost >= ?", minCost)
+	}
+	if raw := c.Query("max_cost"); raw != "" {
+		maxCost, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, errors.New("max_cost must be a number")
+		}
+		query = query.Where("cost <= ?", maxCost)
+	}
+	if c.QueryBool("in_stock") {
+		query = query.Where("stock > 0")
+	}
+	if raw := c.Query("partner"); raw != "" {
+		partnerID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return nil, errors.New("partner must be a partner ID")
+		}
+		query = query.Where("created_by_id = ?", partnerID)
+	}
+	if campaign := strings.TrimSpace(c.Query("campaign")); campaign != "" {
+		query = query.Where("campaign_name ILIKE ?", likePattern(campaign))
+	}
+	return query, nil
+}
+
+// rewardPage filters, sorts and paginates a reward query and writes the page
+// envelope. q does a plain substring match over name and description.
+func rewardPage(c *fiber.Ctx, query *gorm.DB) error {
+	query, err := filterRewards(c, query)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := likePattern(q)
+		query = query.Where("name ILIKE ? OR description ILIKE ?", pattern, pattern)
+	}
+	sort, ok := rewardSorts[c.Query("sort", "newest")]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "sort must be newest, oldest, cost_asc, cost_desc or name"})
+	}
+	base := query.Model(&models.Reward{}).Session(&gorm.Session{})
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not find Rewards"})
+	}
+	paged, err := sort.paginate(base, c.Query("cursor"), "id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cursor"})
+	}
+	limit := pageLimit(c)
+	rewards := []models.Reward{}
+	if err := paged.Limit(limit + 1).Find(&rewards).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not find Rewards"})
+	}
+	next := ""
+	if len(rewards) > limit {
+		rewards = rewards[:limit]
+		last := rewards[limit-1]
+		switch sort.column {
+		case "cost":
+			next = encodeCursor(last.Cost, last.ID)
+		case "name":
+			next = encodeCursor(last.Name, last.ID)
+		default:
+			next = encodeCursor(nil, last.ID)
+		}
+	}
+	return c.JSON(pageEnvelope(rewards, next, total))
+}
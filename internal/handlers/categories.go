@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"errors"
+	"strconv"
+	"strings"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var (
+	errUnknownCategory = errors.New("unknown category")
+	errCategoryCycle   = errors.New("category cannot be nested under itself")
+)
+
+// categoryNode is a category with its subcategories, as served by the tree endpoint
+type categoryNode struct {
+	models.Category
+	Children []*categoryNode `json:"children"`
+}
+
+// categoryTree nests all categories under their parents, siblings in display order
+func categoryTree() ([]*categoryNode, error) {
+	var categories []models.Category
+	if err := db.DB.Order("display_order, name").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	nodes := make(map[uint]*categoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &categoryNode{Category: category, Children: []*categoryNode{}}
+	}
+	roots := []*categoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+func derefID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// categoryWithDescendants returns the IDs of a category and everything nested below it
+func categoryWithDescendants(tx *gorm.DB, id uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Raw(`WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT categories.id FROM categories JOIN tree ON categories.parent_id = tree.id
+		) SELECT id FROM tree`, id).Scan(&ids).Error
+	return ids, err
+}
+
+// findCategory looks a category up by ID, slug or case-insensitive name
+func findCategory(tx *gorm.DB, ref string) (models.Category, error) {
+	var category models.Category
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		err = tx.First(&category, id).Error
+		return category, err
+	}
+	err := tx.Where("slug = ? OR LOWER(name) = LOWER(?)", utils.Slugify(ref), ref).First(&category).Error
+	return category, err
+}
+
+// applyRewardCategory points the reward at a managed category. A changed
+// category_id wins over a changed category name; the name is then rewritten to
+// the category's canonical one. Clearing both leaves the reward uncategorised.
+func applyRewardCategory(tx *gorm.DB, reward *models.Reward, previousID *uint, previousName string) error {
+	var category models.Category
+	switch {
+	case reward.CategoryID != nil && derefID(reward.CategoryID) != derefID(previousID):
+		if err := tx.First(&category, *reward.CategoryID).Error; err != nil {
+			return errUnknownCategory
+		}
+	case reward.Category != previousName || (reward.CategoryID == nil && reward.Category != ""):
+		if strings.TrimSpace(reward.Category) == "" {
+			reward.CategoryID = nil
+			reward.Category = ""
+			return nil
+		}
+		found, err := findCategory(tx, reward.Category)
+		if err != nil {
+			return errUnknownCategory
+		}
+		category = found
+	default:
+		return nil
+	}
+	reward.CategoryID = &category.ID
+	reward.Category = category.Name
+	return nil
+}
+
+// checkCategoryParent makes sure the parent exists and is not the category itself or one of its descendants
+func checkCategoryParent(tx *gorm.DB, categoryID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	var parent models.Category
+	if err := tx.First(&parent, *parentID).Error; err != nil {
+		return errUnknownCategory
+	}
+	if categoryID == 0 {
+		return nil
+	}
+	descendants, err := categoryWithDescendants(tx, categoryID)
+	if err != nil {
+		return err
+	}
+	for _, id := range descendants {
+		if id == parent.ID {
+			return errCategoryCycle
+		}
+	}
+	return nil
+}
+
+// ListCategories returns the category tree
+func ListCategories(c *fiber.Ctx) error {
+	tree, err := categoryTree()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch categories"})
+	}
+	return c.JSON(tree)
+}
+
+// saveCategory validates and stores a category, reporting failures to the client
+func saveCategory(c *fiber.Ctx, category *models.Category, status int) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+	}
+	category.Slug = utils.Slugify(category.Slug)
+	if category.Slug == "" {
+		category.Slug = utils.Slugify(category.Name)
+	}
+	if category.Slug == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "slug must contain letters or digits"})
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryParent(tx, category.ID, category.ParentID); err != nil {
+			return err
+		}
+		if err := tx.Save(category).Error; err != nil {
+			return err
+		}
+		// Keep the denormalised name on rewards in step with renames
+		return tx.Model(&models.Reward{}).Where("category_id = ?", category.ID).Update("category", category.Name).Error
+	})
+	switch {
+	case errors.Is(err, errUnknownCategory):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parent category not found"})
+	case errors.Is(err, errCategoryCycle):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errCategoryCycle.Error()})
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A category with this slug already exists"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save category"})
+	}
+	return c.Status(status).JSON(category)
+}
+
+// AdminAddCategory creates a category, optionally nested under a parent
+func AdminAddCategory(c *fiber.Ctx) error {
+	var category models.Category
+	if err := c.BodyParser(&category); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	category.ID = 0
+	return saveCategory(c, &category, fiber.StatusCreated)
+}
+
+// AdminUpdateCategory renames, moves or reorders a category
+func AdminUpdateCategory(c *fiber.Ctx) error {
+	categoryID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid category ID"})
+	}
+	var category models.Category
+	if err := db.DB.First(&category, categoryID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Category not found"})
+	}
+	if err := c.BodyParser(&category); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	category.ID = uint(categoryID)
+	return saveCategory(c, &category, fiber.StatusOK)
+}
+
+// AdminDeleteCategory deletes a category that has no subcategories and no rewards
+func AdminDeleteCategory(c *fiber.Ctx) error {
+	categoryID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid category ID"})
+	}
+	var children, rewards int64
+	db.DB.Model(&models.Category{}).Where("parent_id = ?", categoryID).Count(&children)
+	db.DB.Model(&models.Reward{}).Where("category_id = ?", categoryID).Count(&rewards)
+	if children > 0 || rewards > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Move its subcategories and rewards before deleting this category"})
+	}
+	result := db.DB.Delete(&models.Category{}, categoryID)
+	// A reward or subcategory added since the check above still holds the category
+	if errors.Is(result.Error, gorm.ErrForeignKeyViolated) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Move its subcategories and rewards before deleting this category"})
+	}
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete category"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Category not found"})
+	}
+	return c.JSON(fiber.Map{"message": "Category deleted successfully"})
+}
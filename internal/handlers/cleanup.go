@@ -4,15 +4,16 @@ import (
 	"time"
 	"log"
 	"authapi/internal/db"
+	"authapi/internal/ledger"
 	"authapi/internal/models"
 )
 
-// Delete users who never verified and are older than 30 minutes
+// Delete users who never verified, are older than 30 minutes and hold no live OTP
 func CleanUpUnverifiedUsers() {
 	expiry := time.Now().Add(-30 * time.Minute)
 
 	result := db.DB.
-		Where("is_verified = ? AND created_at < ?", false, expiry).
+		Where("is_verified = ? AND created_at < ? AND otp_expires_at < ?", false, expiry, time.Now()).
 		Delete(&models.User{})
 
 	if result.Error != nil {
@@ -24,3 +25,44 @@ func CleanUpUnverifiedUsers() {
 	}
 }
 
+
+// Delete refresh tokens that can no longer be used
+func CleanUpExpiredRefreshTokens() {
+	result := db.DB.
+		Where("expires_at < ?", time.Now()).
+		Delete(&models.RefreshToken{})
+
+	if result.Error != nil {
+		log.Println("Refresh token cleanup failed:", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Cleaned up %d expired refresh tokens\n", result.RowsAffected)
+	}
+}
+
+// Delete stored idempotent responses whose replay window has passed
+func CleanUpExpiredIdempotencyKeys() {
+	result := db.DB.
+		Where("expires_at < ?", time.Now()).
+		Delete(&models.IdempotencyKey{})
+
+	if result.Error != nil {
+		log.Println("Idempotency key cleanup failed:", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Cleaned up %d expired idempotency keys\n", result.RowsAffected)
+	}
+}
+
+// Expire points left in lots that have passed their expiry date
+func ExpirePoints() {
+	expired, err := ledger.ExpireLots(db.DB)
+	if err != nil {
+		log.Println("Points expiry failed:", err)
+	}
+	if expired > 0 {
+		log.Printf("Expired %d points\n", expired)
+	}
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"authapi/internal/barcode"
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// findPartnerCoupon loads the redemption holding a coupon code, limited to rewards the partner owns
+func findPartnerCoupon(code string, partnerID uint) (models.Transaction, models.Reward, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	var t models.Transaction
+	var reward models.Reward
+	err := db.DB.
+		Joins("JOIN rewards ON rewards.id = transactions.reward_id").
+		Where("transactions.coupon_code = ? AND transactions.type = ? AND rewards.created_by_id = ?", code, models.TransactionRedemption, partnerID).
+		First(&t).Error
+	if err != nil {
+		return t, reward, err
+	}
+	err = db.DB.First(&reward, t.RewardID).Error
+	return t, reward, err
+}
+
+// couponNotFound distinguishes a mistyped generated code, caught by its check
+// digit, from an unknown one. Partner voucher codes have no check digit.
+func couponNotFound(c *fiber.Ctx, code string) error {
+	if utils.LooksLikeCouponCode(code) && !utils.CheckCouponCode(code) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Coupon code looks mistyped, please check it"})
+	}
+	return c.Status(404).JSON(fiber.Map{"error": "Coupon not found"})
+}
+
+// couponState reports whether a coupon can be used and, if not, why
+func couponState(t models.Transaction) (bool, string) {
+	switch {
+	case t.CouponStatus == models.CouponConsumed:
+		return false, "Coupon already used"
+	case t.CouponStatus == models.CouponExpired || (t.CouponExpiresAt != nil && !t.CouponExpiresAt.After(time.Now())):
+		return false, "Coupon has expired"
+	case t.CouponStatus == models.CouponVoid || t.Status == models.StatusRefunded || t.Status == models.StatusReleased:
+		return false, "Coupon has been cancelled"
+	case t.Status != models.StatusCompleted:
+		return false, "Redemption not completed"
+	}
+	return true, ""
+}
+
+func couponResponse(t models.Transaction, reward models.Reward) fiber.Map {
+	valid, reason := couponState(t)
+	response := fiber.Map{
+		"coupon_code":       t.CouponCode,
+		"valid":             valid,
+		"status":            t.CouponStatus,
+		"reward":            fiber.Map{"id": reward.ID, "name": reward.Name},
+		"redeemed_at":       t.CreatedAt,
+		"consumed_at":       t.ConsumedAt,
+		"consumed_location": t.ConsumedLocation,
+		"expires_at":        t.CouponExpiresAt,
+	}
+	if !valid {
+		response["reason"] = reason
+	}
+	return response
+}
+
+// PartnerLookupCoupon retrieves a coupon presented to the partner and whether it is still usable
+func PartnerLookupCoupon(c *fiber.Ctx) error {
+	partnerID := uint(c.Locals("user_id").(float64))
+	t, reward, err := findPartnerCoupon(c.Params("code"), partnerID)
+	if err != nil {
+		return couponNotFound(c, c.Params("code"))
+	}
+	return c.JSON(couponResponse(t, reward))
+}
+
+// PartnerConsumeCoupon marks a coupon as used. Each coupon can be consumed once.
+func PartnerConsumeCoupon(c *fiber.Ctx) error {
+	partnerID := uint(c.Locals("user_id").(float64))
+	var input struct {
+		Location string `json:"location"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	t, reward, err := findPartnerCoupon(c.Params("code"), partnerID)
+	if err != nil {
+		return couponNotFound(c, c.Params("code"))
+	}
+	if valid, reason := couponState(t); !valid {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": reason})
+	}
+
+	// Conditional update so two counters scanning the same coupon cannot both succeed
+	now := time.Now()
+	result := db.DB.Model(&models.Transaction{}).
+		Where("id = ? AND status = ? AND coupon_status = ?", t.ID, models.StatusCompleted, models.CouponActive).
+		Where("coupon_expires_at IS NULL OR coupon_expires_at > ?", now).
+		Updates(map[string]interface{}{
+			"coupon_status":     models.CouponConsumed,
+			"consumed_at":       now,
+			"consumed_location": input.Location,
+		})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not consume coupon"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Coupon already used"})
+	}
+	t.CouponStatus = models.CouponConsumed
+	t.ConsumedAt = &now
+	t.ConsumedLocation = input.Location
+	return c.JSON(fiber.Map{"message": "Coupon consumed", "coupon": couponResponse(t, reward)})
+}
+
+// couponAttempts bounds how many codes are tried before giving up on a collision
+const couponAttempts = 5
+
+// issueCoupon generates a coupon for the reward and hands it to write, retrying
+// with a fresh code if it collides with an existing one. Each attempt runs in a
+// savepoint so a unique violation does not abort the surrounding transaction.
+// Rewards with partner-supplied vouchers get the next unused voucher instead; a
+// voucher whose code is already a coupon stays claimed and is skipped.
+func issueCoupon(tx *gorm.DB, reward models.Reward, write func(tx *gorm.DB, code string) error) (string, error) {
+	if reward.ExternalVouchers {
+		skipped := false
+		for {
+			voucher, err := claimVoucher(tx, reward.ID)
+			if err != nil {
+				return "", err
+			}
+			// Vouchers uploaded before codes were normalised may be lower case
+			code := strings.ToUpper(voucher.Code)
+			err = tx.Transaction(func(sp *gorm.DB) error {
+				return write(sp, code)
+			})
+			if err == nil {
+				if skipped {
+					return code, syncVoucherStock(tx, reward.ID)
+				}
+				return code, nil
+			}
+			if !errors.Is(err, gorm.ErrDuplicatedKey) {
+				return "", err
+			}
+			log.Printf("Voucher %d of reward %d is already an issued coupon, skipping it\n", voucher.ID, reward.ID)
+			skipped = true
+		}
+	}
+	prefix := utils.CouponPrefix(reward.Name)
+	for attempt := 0; attempt < couponAttempts; attempt++ {
+		code := utils.GenerateCouponCode(prefix)
+		err := tx.Transaction(func(sp *gorm.DB) error {
+			return write(sp, code)
+		})
+		if err == nil {
+			return code, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return "", err
+		}
+	}
+	return "", errors.New("could not generate a unique coupon code")
+}
+
+// GetTransactionCode renders the coupon of one of the user's redemptions as a
+// QR code carrying a signed payload (type=qr, the default) or as a Code 128
+// barcode of the plain coupon code (type=code128), in PNG or SVG format.
+func GetTransactionCode(c *fiber.Ctx) error {
+	userID := uint(c.Locals("user_id").(float64))
+	transactionID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid transaction ID"})
+	}
+	var t models.Transaction
+	if err := db.DB.Where("id = ? AND user_id = ? AND type = ?", transactionID, userID, models.TransactionRedemption).First(&t).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Transaction not found"})
+	}
+	if t.CouponCode == "" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Transaction has no coupon yet"})
+	}
+	format := c.Query("format", "png")
+	if format != "png" && format != "svg" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be png or svg"})
+	}
+
+	var image []byte
+	switch c.Query("type", "qr") {
+	case "qr":
+		payload, err := utils.SignCouponPayload(t.ID, t.CouponCode)
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Coupon signing is not configured"})
+		}
+		qr, err := barcode.EncodeQR([]byte(payload))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not render code"})
+		}
+		if format == "svg" {
+			image = qr.SVG()
+		} else if image, err = qr.PNG(8); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not render code"})
+		}
+	case "code128":
+		modules, err := barcode.EncodeCode128(t.CouponCode)
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Coupon code cannot be shown as a barcode"})
+		}
+		if format == "svg" {
+			image = barcode.LinearSVG(modules, 60)
+		} else if image, err = barcode.LinearPNG(modules, 3, 120); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not render code"})
+		}
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type must be qr or code128"})
+	}
+
+	if format == "svg" {
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+	} else {
+		c.Set(fiber.HeaderContentType, "image/png")
+	}
+	return c.Send(image)
+}
+
+// PartnerVerifyCoupon checks the signature of a scanned QR payload and returns the coupon it names
+func PartnerVerifyCoupon(c *fiber.Ctx) error {
+	partnerID := uint(c.Locals("user_id").(float64))
+	var input struct {
+		Payload string `json:"payload"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	transactionID, code, ok := utils.VerifyCouponPayload(input.Payload)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid coupon signature"})
+	}
+	t, reward, err := findPartnerCoupon(code, partnerID)
+	if err != nil || t.ID != transactionID {
+		return c.Status(404).JSON(fiber.Map{"error": "Coupon not found"})
+	}
+	return c.JSON(couponResponse(t, reward))
+}
+
+// GetCouponSigningKey publishes the key for verifying QR payloads offline
+func GetCouponSigningKey(c *fiber.Ctx) error {
+	publicKey, err := utils.CouponPublicKey()
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Coupon signing is not configured"})
+	}
+	return c.JSON(fiber.Map{
+		"algorithm":  "Ed25519",
+		"public_key": publicKey,
+		"format":     "RX1.<transaction_id>.<coupon_code>.<base64url signature of the preceding text>",
+	})
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/earning"
+	"authapi/internal/ledger"
+	"authapi/internal/models"
+	"authapi/internal/tiers"
+	"errors"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errDuplicateEvent = errors.New("event already recorded")
+
+// PartnerPostEarnEvent credits a user's wallet for a qualifying event reported by the partner
+func PartnerPostEarnEvent(c *fiber.Ctx) error {
+	partnerID := uint(c.Locals("user_id").(float64))
+	var input struct {
+		Email      string  `json:"email"`
+		Action     string  `json:"action"`
+		Amount     float64 `json:"amount"`
+		Category   string  `json:"category"`
+		ExternalID string  `json:"external_id"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if input.Email == "" || input.Action == "" || input.ExternalID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email, action and external_id required"})
+	}
+	if input.Amount < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Amount cannot be negative"})
+	}
+
+	var user models.User
+	if err := db.DB.Where("email = ? AND is_verified = ?", input.Email, true).First(&user).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	var rules []models.EarnRule
+	// Partners only ever earn points through their own rules
+	if err := db.DB.Where("active = ? AND action = ? AND partner_id = ?", true, input.Action, partnerID).Find(&rules).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not load earn rules"})
+	}
+	event := earning.Event{
+		Action:    input.Action,
+		Amount:    input.Amount,
+		Category:  input.Category,
+		PartnerID: partnerID,
+	}
+
+	var record models.EarnEvent
+	var awards []earning.Award
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the user serialises concurrent events so daily caps hold
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, user.ID).Error; err != nil {
+			return err
+		}
+		var existing int64
+		tx.Model(&models.EarnEvent{}).Where("partner_id = ? AND external_id = ?", partnerID, input.ExternalID).Count(&existing)
+		if existing > 0 {
+			return errDuplicateEvent
+		}
+		earnedToday, err := earnedTodayByRule(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		awards = earning.Evaluate(rules, event, earnedToday, tiers.ByName(user.Tier).EarnMultiplier)
+
+		record = models.EarnEvent{
+			PartnerID:  partnerID,
+			ExternalID: input.ExternalID,
+			UserID:     user.ID,
+			Action:     input.Action,
+			Amount:     input.Amount,
+			Category:   input.Category,
+			Points:     earning.Total(awards),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		for _, a := range awards {
+			award := models.EarnAward{EventID: record.ID, RuleID: a.RuleID, UserID: user.ID, Points: a.Points}
+			if err := tx.Create(&award).Error; err != nil {
+				return err
+			}
+		}
+		if record.Points == 0 {
+			return nil
+		}
+		if err := ledger.Credit(tx, user.ID, record.Points, ledger.ReasonEarn, nil); err != nil {
+			return err
+		}
+		return refreshTier(tx, &user, true)
+	})
+	// A concurrent request with the same external_id can pass the check above and
+	// only be stopped by the unique index
+	if errors.Is(err, errDuplicateEvent) || errors.Is(err, gorm.ErrDuplicatedKey) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Event already recorded"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not record event"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Event recorded", "event": record, "awards": awards})
+}
+
+// earnedTodayByRule sums the points each rule has awarded the user since midnight
+func earnedTodayByRule(tx *gorm.DB, userID uint) (map[uint]int, error) {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var rows []struct {
+		RuleID uint
+		Points int
+	}
+	err := tx.Model(&models.EarnAward{}).
+		Select("rule_id, SUM(points) AS points").
+		Where("user_id = ? AND created_at >= ?", userID, startOfDay).
+		Group("rule_id").
+		Scan(&rows).Error
+	earned := make(map[uint]int, len(rows))
+	for _, r := range rows {
+		earned[r.RuleID] = r.Points
+	}
+	return earned, err
+}
+
+// isPartner reports whether the user ID belongs to a partner account
+func isPartner(userID uint) bool {
+	var count int64
+	db.DB.Model(&models.User{}).Where("id = ? AND role = ?", userID, "partner").Count(&count)
+	return count > 0
+}
+
+// AdminAddEarnRule creates a new earn rule
+func AdminAddEarnRule(c *fiber.Ctx) error {
+	var rule models.EarnRule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	rule.ID = 0
+	if err := earning.ValidateRule(rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !isPartner(rule.PartnerID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "partner_id must refer to a partner"})
+	}
+	if err := db.DB.Create(&rule).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save earn rule"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(rule)
+}
+
+// AdminListEarnRules retrieves all earn rules
+func AdminListEarnRules(c *fiber.Ctx) error {
+	var rules []models.EarnRule
+	db.DB.Order("id").Find(&rules)
+	return c.JSON(rules)
+}
+
+// AdminUpdateEarnRule replaces an existing earn rule
+func AdminUpdateEarnRule(c *fiber.Ctx) error {
+	ruleID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid rule ID"})
+	}
+	var rule models.EarnRule
+	if err := db.DB.First(&rule, ruleID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Earn rule not found"})
+	}
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	rule.ID = uint(ruleID)
+	if err := earning.ValidateRule(rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !isPartner(rule.PartnerID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "partner_id must refer to a partner"})
+	}
+	if err := db.DB.Save(&rule).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update earn rule"})
+	}
+	return c.JSON(rule)
+}
+
+// AdminDeleteEarnRule deletes an earn rule
+func AdminDeleteEarnRule(c *fiber.Ctx) error {
+	ruleID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid rule ID"})
+	}
+	if err := db.DB.Delete(&models.EarnRule{}, ruleID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete earn rule"})
+	}
+	return c.JSON(fiber.Map{"message": "Earn rule deleted successfully"})
+}
@@ -2,23 +2,50 @@ package handlers
 
 import (
 	"authapi/internal/db"
+	"authapi/internal/ledger"
 	"authapi/internal/models"
+	"authapi/internal/tiers"
 	"authapi/internal/utils"
+	"errors"
+	"fmt"
 	"strconv"
-	"sync"
 	"time"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	errUserNotFound   = errors.New("user not found")
+	errRewardNotFound = errors.New("reward not found")
+	errOutOfStock     = errors.New("reward out of stock")
+	errTierRequired   = errors.New("reward requires a higher tier")
+)
+
+// signupBonusPoints are credited to every new account
+const signupBonusPoints = 400
+
 // RegisterUser handles user registration
 func RegisterUser(c *fiber.Ctx) error {
 	var u models.User
 	if err := c.BodyParser(&u); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
-	if u.Role != "partner" && u.Role != "user" {
-		u.Role = "user"
+	// Partners are onboarded through a reviewed application, never by signing up
+	u.Role = "user"
+	// Tiers are earned, never chosen at signup
+	u.Tier = ""
+	// Verification only happens through VerifyOTP, which also releases referral bonuses
+	u.IsVerified = false
+	// The user gets their own referral_code below; a referrer's code arrives separately
+	u.ReferralCode = ""
+	var referral struct {
+		ReferredByCode string `json:"referred_by_code"`
+	}
+	if err := c.BodyParser(&referral); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
+	referrerCode := referral.ReferredByCode
 	// checking if the user exists
     var existing models.User
 	result := db.DB.Where("email = ?", u.Email).First(&existing)
@@ -27,7 +54,6 @@ func RegisterUser(c *fiber.Ctx) error {
 			"error": "Email already Registered",
 		})
 	}
-	u.Points = 400
 	// password hasshing
 	hashedPassword, err := utils.HashingPassword(u.Password)
 	if err != nil {
@@ -35,14 +61,30 @@ func RegisterUser(c *fiber.Ctx) error {
 	}
 	u.Password = hashedPassword
     // generating the otp
-	u.OTP = utils.GenerateOTP()
-	u.OTPExpiresAt = time.Now().Add(5 * time.Minute)
-    // saves New user 
-	if err := db.DB.Create(&u).Error; err!=nil{
+	otp := issueOTP(&u)
+    // saves New user and credits the signup bonus
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := assignReferralCode(tx, &u); err != nil {
+			return err
+		}
+		if err := tx.Create(&u).Error; err != nil {
+			return err
+		}
+		if referrerCode != "" {
+			if err := recordReferral(tx, u, referrerCode, c.IP()); err != nil {
+				return err
+			}
+		}
+		return ledger.Credit(tx, u.ID, signupBonusPoints, ledger.ReasonSignupBonus, nil)
+	})
+	if errors.Is(err, errInvalidReferralCode) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid referral code"})
+	}
+	if err!=nil{
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save user"})
 	}
 	//  Send OTP to email (mocked)
-	if errmail := utils.SendOTPEmail(u.Email, u.OTP); errmail!=nil{
+	if errmail := utils.SendOTPEmail(u.Email, otp); errmail!=nil{
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send otp to user"})
 	}
 
@@ -69,14 +111,29 @@ func VerifyOTP(c *fiber.Ctx) error {
     if result.Error != nil {
         return c.Status(404).JSON(fiber.Map{"error": "User not found"})
     }
-    if !user.IsVerified && time.Now().After(user.OTPExpiresAt) {
+    if user.IsVerified {
+        return c.Status(400).JSON(fiber.Map{"error": "Account already verified"})
+    }
+    if !takeOTPAttempt(user.ID) {
+        return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many attempts, request a new OTP"})
+    }
+    if time.Now().After(user.OTPExpiresAt) {
         return c.Status(400).JSON(fiber.Map{"error": "OTP expired"})
     }
-    if user.OTP != input.OTP {
+    if !utils.CheckOTP(input.OTP, user.OTP) {
         return c.Status(400).JSON(fiber.Map{"error": "Incorrect OTP"})
     }
     user.IsVerified = true
-    db.DB.Save(&user)
+    clearOTP(&user)
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Save(&user).Error; err != nil {
+            return err
+        }
+        return completeReferral(tx, user)
+    })
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not verify account"})
+    }
     return c.JSON(fiber.Map{"message": "Verification successful"})
 }
 
@@ -104,101 +161,242 @@ func LoginHandler(c *fiber.Ctx) error {
         return c.Status(403).JSON(fiber.Map{"error": "Account not verified"})
     }
 	
-    token, err := utils.GenerateToken(user.ID, user.Role)
+    token, refreshToken, err := startSession(user)
     if err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
     }
-    return c.JSON(fiber.Map{"token": token, "role": user.Role})
+    return c.JSON(fiber.Map{"token": token, "refresh_token": refreshToken, "role": user.Role})
 }
 
-// ListRewards retrieves all available rewards
+// ListRewards retrieves all rewards that are active and inside their campaign
+// window. Tier-exclusive rewards are only listed for logged-in users whose tier
+// qualifies, and once-per-user rewards are hidden after the user redeemed them.
+// Results are filtered, sorted and paginated by the catalog query parameters.
 func ListRewards(c *fiber.Ctx) error {
-	rewards:= []models.Reward{}
-	if result:= db.DB.Find(&rewards); result.Error!=nil{
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Could not find Rewards"})
-	}
-	return c.JSON(rewards)
+	return rewardPage(c, catalogRewards(db.DB, c))
 }
 
-// GetUserWallet retrieves the points of the logged-in user
+// GetUserWallet retrieves the ledger balance and a page of the statement of the logged-in user
 func GetUserWallet(c*fiber.Ctx) error {
 	userID:= uint(c.Locals("user_id").(float64))
 	var user models.User
 	if result:= db.DB.Where("id = ?", userID).First(&user); result.Error!=nil {
 		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
 	}
-	return c.JSON(fiber.Map{"points": user.Points})
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	balance, err := ledger.Balance(db.DB, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch balance"})
+	}
+	entries, total, err := ledger.Statement(db.DB, userID, limit, (page-1)*limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch statement"})
+	}
+	var onHold int
+	db.DB.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(points_used), 0)").
+		Where("user_id = ? AND status = ?", userID, models.StatusPending).
+		Scan(&onHold)
+	expiring, err := ledger.UpcomingExpiries(db.DB, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch expiring points"})
+	}
+	response := fiber.Map{
+		"points":    balance,
+		"on_hold":   onHold,
+		"statement": entries,
+		"page":      page,
+		"limit":     limit,
+		"total":     total,
+		"expiring":  expiring,
+	}
+	if len(expiring) > 0 {
+		next := expiring[0]
+		response["expiry_notice"] = fmt.Sprintf("%d points expiring on %s", next.Points, next.ExpiresOn.Format("2006-01-02"))
+	}
+	return c.JSON(response)
 }
 
-// RedeemReward allows a user to redeem a reward
+// RedeemReward allows a user to redeem a reward. The balance check, stock
+// decrement, transaction insert and ledger debit run in one database
+// transaction with the user and reward rows locked, so concurrent redemptions
+// can neither overspend points nor oversell stock.
 func RedeemReward(c *fiber.Ctx) error{
 	userID:= uint(c.Locals("user_id").(float64))
-	var t models.Transaction
-	if err:= c.BodyParser(&t); err!=nil{
+	var input struct {
+		RewardID uint `json:"reward_id"`
+	}
+	if err:= c.BodyParser(&input); err!=nil{
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	var user   models.User
+	var t models.Transaction
 	var reward models.Reward
-	var wg     sync.WaitGroup
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		db.DB.First(&user, userID)
-	}()
-	go func() {
-		defer wg.Done()
-		db.DB.First(&reward, t.RewardID)
-	}()
-	wg.Wait()
-	if user.Points<reward.Cost{
+	var remaining int
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock order is always user, then reward
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return errUserNotFound
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, input.RewardID).Error; err != nil {
+			return errRewardNotFound
+		}
+		if err := checkRewardWindow(reward, time.Now()); err != nil {
+			return err
+		}
+		if !tiers.Eligible(user.Tier, reward.MinTier) {
+			return errTierRequired
+		}
+		if err := checkAutoExpire(tx, reward, userID); err != nil {
+			return err
+		}
+		if user.Points < reward.Cost {
+			return ledger.ErrInsufficientPoints
+		}
+		if reward.Stock <= 0 {
+			return errOutOfStock
+		}
+		if err := tx.Model(&reward).UpdateColumn("stock", gorm.Expr("stock - 1")).Error; err != nil {
+			return err
+		}
+		if reward.RequiresConfirmation {
+			var err error
+			if t, err = placeHold(tx, userID, reward); err != nil {
+				return err
+			}
+			return tx.Model(&models.Reward{}).Where("id = ?", reward.ID).Pluck("stock", &remaining).Error
+		}
+		t = models.Transaction{
+			Type:       models.TransactionRedemption,
+			UserID:     userID,
+			RewardID:   reward.ID,
+			Status:     models.StatusCompleted,
+			CouponStatus: models.CouponActive,
+			CouponExpiresAt: couponExpiry(reward, time.Now()),
+			PointsUsed: reward.Cost,
+			CreatedAt:  time.Now(),
+		}
+		if _, err := issueCoupon(tx, reward, func(tx *gorm.DB, code string) error {
+			t.ID = 0
+			t.CouponCode = code
+			return tx.Create(&t).Error
+		}); err != nil {
+			return err
+		}
+		if err := ledger.Debit(tx, userID, reward.Cost, ledger.ReasonRedemption, &t.ID); err != nil {
+			return err
+		}
+		// Issuing a voucher resyncs the stock with the codes left, so read it back
+		return tx.Model(&models.Reward{}).Where("id = ?", reward.ID).Pluck("stock", &remaining).Error
+	})
+	switch {
+	case errors.Is(err, errUserNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, errRewardNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Reward not found"})
+	case errors.Is(err, errRewardInactive), errors.Is(err, errRewardEnded):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "Reward is no longer available"})
+	case errors.Is(err, errRewardNotStarted):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Reward campaign has not started yet"})
+	case errors.Is(err, errTierRequired):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Reward is exclusive to a higher tier"})
+	case errors.Is(err, errAlreadyRedeemed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Reward can only be redeemed once"})
+	case errors.Is(err, ledger.ErrInsufficientPoints):
 		return c.Status(400).JSON(fiber.Map{"error": "Insuficient Points"})
-	}else if reward.Stock<=0 {
-		return  c.Status(400).JSON(fiber.Map{"error": "Reward out of stock"})
-	}
-	t.UserID = userID
-	user.Points-=reward.Cost
-	reward.Stock-=1
-	t.Status = "Completed"
-	t.PointsUsed = reward.Cost
-	t.CouponCode = utils.GenerateCouponCode(reward.Name[:3])
-	t.CreatedAt = time.Now()
-	db.DB.Save(&user)
-	db.DB.Save(&reward)
-	db.DB.Create(&t)
+	case errors.Is(err, errOutOfStock), errors.Is(err, errNoVouchers):
+		return c.Status(400).JSON(fiber.Map{"error": "Reward out of stock"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not redeem reward"})
+	}
+	alertLowVouchers(reward, remaining)
+	if t.Status == models.StatusPending {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Redemption pending partner confirmation", "transaction": t})
+	}
 	return c.JSON(fiber.Map{"message": "Reward redeemed", "transaction": t})
 }
 
-// GetUserTransactions retrieves all transactions for the logged-in user
+// GetUserTransactions retrieves a page of transactions for the logged-in user,
+// including transfers they received. They can be filtered by type and status.
 func GetUserTransactions(c *fiber.Ctx) error {
 	userID:=uint(c.Locals("user_id").(float64))
-	var transactions []models.Transaction
-	db.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&transactions)
-	return c.JSON(transactions)
+	query := db.DB.Model(&models.Transaction{}).Where("user_id = ? OR counterparty_id = ?", userID, userID)
+	if kind := c.Query("type"); kind != "" {
+		query = query.Where("type = ?", kind)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	sort, ok := transactionSorts[c.Query("sort", "newest")]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "sort must be newest or oldest"})
+	}
+	base := query.Session(&gorm.Session{})
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch transactions"})
+	}
+	paged, err := sort.paginate(base, c.Query("cursor"), "id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cursor"})
+	}
+	limit := pageLimit(c)
+	transactions := []models.Transaction{}
+	if err := paged.Limit(limit + 1).Find(&transactions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch transactions"})
+	}
+	next := ""
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		last := transactions[limit-1]
+		next = encodeCursor(last.CreatedAt, last.ID)
+	}
+	return c.JSON(pageEnvelope(transactions, next, total))
 }
 
 // AdminAddReward allows the admin to add a new reward
 func AdminAddReward(c *fiber.Ctx) error {
-	role:=c.Locals("role").(string)
 	userID:= uint(c.Locals("user_id").(float64))
-	if role!="admin"{
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid Access"})
-	}
 	var reward models.Reward
 	if err:=c.BodyParser(&reward); err!=nil{
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
+	if !isValidMinTier(reward.MinTier) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid min_tier"})
+	}
+	if !validRewardWindow(reward) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end_date must be after start_date"})
+	}
+	if err := applyRewardCategory(db.DB, &reward, nil, ""); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown category"})
+	}
 	reward.CreatedByID = userID
-	db.DB.Create(&reward)
+	reward.IsActive = !rewardEnded(reward, time.Now())
+	// Rewards added by admins need no review
+	now := time.Now()
+	reward.ApprovalStatus = models.RewardApproved
+	reward.RejectionReason = ""
+	reward.SubmittedAt = nil
+	reward.ReviewedByID = userID
+	reward.ReviewedAt = &now
+	// Voucher inventory is switched on by uploading codes
+	reward.ExternalVouchers = false
+	if err := createReward(db.DB, &reward); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to add reward"})
+	}
 	return c.JSON(fiber.Map{"message": "Reward added"})
 }
 
 // AdminAddPartner creates a new partner account by the admin
 func AdminAddPartner(c *fiber.Ctx) error {
-	role:=c.Locals("role").(string)
-	if role!="admin"{
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid Access"})
-	}
 	u := new(models.User)
 	if err := c.BodyParser(u); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
@@ -207,14 +405,42 @@ func AdminAddPartner(c *fiber.Ctx) error {
 	u.Password, _ = utils.HashingPassword(u.Password)
 	u.Role = "partner"
 	u.IsVerified = true
+	u.Tier = ""
+	if err := assignReferralCode(db.DB, u); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create partner"})
+	}
 	db.DB.Create(u)
 	return c.JSON(fiber.Map{"message": "Partner account created"})
 }
 
+// ReconcileLedger lists users whose cached points disagree with the points ledger
+func ReconcileLedger(c *fiber.Ctx) error {
+	mismatches, err := ledger.Reconcile(db.DB)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reconcile ledger"})
+	}
+	return c.JSON(fiber.Map{"mismatches": mismatches, "count": len(mismatches)})
+}
+
+// partnerView is a partner account as listed to staff. It keeps the keys of
+// the User JSON but never carries the password hash.
+type partnerView struct {
+	ID           uint      `json:"ID"`
+	CreatedAt    time.Time `json:"CreatedAt"`
+	UpdatedAt    time.Time `json:"UpdatedAt"`
+	Username     string    `json:"username"`
+	Email        string    `json:"email"`
+	Role         string    `json:"role"`
+	Points       int       `json:"points"`
+	IsVerified   bool      `json:"is_verified"`
+	Tier         string    `json:"tier"`
+	ReferralCode string    `json:"referral_code"`
+}
+
 // GetAllPartners retrieves all partners from the database
 func GetAllPartners(c *fiber.Ctx) error {
-	var partners []models.User
-	if err := db.DB.Where("role = ?", "partner").Find(&partners).Error; err != nil {
+	partners := []partnerView{}
+	if err := db.DB.Model(&models.User{}).Where("role = ?", "partner").Find(&partners).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch partners"})
 	}
 	return c.JSON(partners)
@@ -234,13 +460,40 @@ func AdminUpdateReward(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Reward not found"})
 	}
 
+	previousEnd := reward.EndDate
+	previousCategoryID, previousCategory := reward.CategoryID, reward.Category
+	review := reward
 	if err := c.BodyParser(&reward); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
+	// Approval only changes through the review endpoints
+	reward.ApprovalStatus = review.ApprovalStatus
+	reward.RejectionReason = review.RejectionReason
+	reward.SubmittedAt = review.SubmittedAt
+	reward.ReviewedByID = review.ReviewedByID
+	reward.ReviewedAt = review.ReviewedAt
+	if !isValidMinTier(reward.MinTier) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid min_tier"})
+	}
+	if !validRewardWindow(reward) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end_date must be after start_date"})
+	}
+	if err := applyRewardCategory(db.DB, &reward, previousCategoryID, previousCategory); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown category"})
+	}
+	// Moving the end date brings an ended reward back, or ends it right away
+	if !reward.EndDate.Equal(previousEnd) {
+		reward.IsActive = !rewardEnded(reward, time.Now())
+	}
 
 	if err := db.DB.Save(&reward).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update reward"})
 	}
+	// Stock of voucher rewards always follows their remaining codes
+	if reward.ExternalVouchers {
+		syncVoucherStock(db.DB, reward.ID)
+		db.DB.First(&reward, reward.ID)
+	}
 
 	return c.JSON(fiber.Map{"message": "Reward updated successfully", "reward": reward})
 }
@@ -254,6 +507,9 @@ func AdminDeleteReward(c *fiber.Ctx) error {
 	}
 	rewardID := uint(idUint64)
 
+	if hasPendingRedemptions(rewardID) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Reward has pending redemptions, confirm or reject them first"})
+	}
 	if err := db.DB.Delete(&models.Reward{}, rewardID).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete reward"})
 	}
@@ -279,7 +535,7 @@ func GetAdminAnalytics(c *fiber.Ctx) error {
 	db.DB.Model(&models.Reward{}).Count(&totalRewards)
 
 	var totalRedemptions int64
-	db.DB.Model(&models.Transaction{}).Count(&totalRedemptions)
+	db.DB.Model(&models.Transaction{}).Where("type = ?", models.TransactionRedemption).Count(&totalRedemptions)
 
 	// Find the most active partners
 	var mostActivePartners []ActiveMerchant
@@ -305,28 +561,40 @@ func GetAdminAnalytics(c *fiber.Ctx) error {
 // PartnerAddReward adds a new reward created by the logged-in partner
 func PartnerAddReward(c *fiber.Ctx) error {
 	//Get Logged-in Partner
-	role:=c.Locals("role").(string)
 	userID:= uint(c.Locals("user_id").(float64))
-	if role!="partner"{
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid Access"})
-	}
 	r := new(models.Reward)
 	if err := c.BodyParser(r); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
 	}
+	if !isValidMinTier(r.MinTier) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid min_tier"})
+	}
+	if !validRewardWindow(*r) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end_date must be after start_date"})
+	}
+	if err := applyRewardCategory(db.DB, r, nil, ""); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown category"})
+	}
 	r.CreatedByID = userID
-	db.DB.Create(r)
-	return c.JSON(fiber.Map{"message": "Partner reward added"})
+	r.IsActive = !rewardEnded(*r, time.Now())
+	// Voucher inventory is switched on by uploading codes
+	r.ExternalVouchers = false
+	// Partner rewards stay out of the catalog until an admin approves them
+	r.ApprovalStatus = models.RewardDraft
+	r.RejectionReason = ""
+	r.SubmittedAt = nil
+	r.ReviewedByID = 0
+	r.ReviewedAt = nil
+	if err := createReward(db.DB, r); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to add reward"})
+	}
+	return c.JSON(fiber.Map{"message": "Partner reward saved as draft, submit it for review to publish it", "reward": r})
 }
 
 // PartnerUpdateReward updates a reward created by the logged-in partner
 func PartnerUpdateReward(c *fiber.Ctx) error {
 	//Get Logged-in Partner
-	role:=c.Locals("role").(string)
 	userID:= uint(c.Locals("user_id").(float64))
-	if role!="partner"{
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid Access"})
-	}
 	//Get Reward ID from URL
 	rewardID, err := c.ParamsInt("id")
 	if err != nil {
@@ -345,18 +613,34 @@ func PartnerUpdateReward(c *fiber.Ctx) error {
 	if err := c.BodyParser(&updatedData); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
+	// Fields whose zero value is meaningful are only changed when present in the body
+	var toggles struct {
+		AutoExpireAfterRedemption *bool   `json:"auto_expire_after_redemption"`
+		IsActive                  *bool   `json:"is_active"`
+		MinTier                   *string `json:"min_tier"`
+		Stock                     *int    `json:"stock"`
+	}
+	if err := c.BodyParser(&toggles); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	before := reward
 	// Update the fields
 	if updatedData.Name != "" {
 		reward.Name = updatedData.Name
 	}
-	if updatedData.Category != "" {
+	if updatedData.Category != "" || updatedData.CategoryID != nil {
+		if err := applyRewardCategory(db.DB, &updatedData, nil, ""); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown category"})
+		}
+		reward.CategoryID = updatedData.CategoryID
 		reward.Category = updatedData.Category
 	}
 	if updatedData.Cost > 0 {
 		reward.Cost = updatedData.Cost
 	}
-	if updatedData.Stock >= 0 {
-		reward.Stock = updatedData.Stock
+	// Stock of voucher rewards is derived from their remaining codes
+	if toggles.Stock != nil && *toggles.Stock >= 0 && !reward.ExternalVouchers {
+		reward.Stock = *toggles.Stock
 	}
 	if updatedData.Discount > 0 {
 		reward.Discount = updatedData.Discount
@@ -367,6 +651,41 @@ func PartnerUpdateReward(c *fiber.Ctx) error {
 	if updatedData.Description != "" {
 		reward.Description = updatedData.Description
 	}
+	// An empty min_tier opens the reward to every tier again
+	if toggles.MinTier != nil {
+		if !isValidMinTier(*toggles.MinTier) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid min_tier"})
+		}
+		reward.MinTier = *toggles.MinTier
+	}
+	if !updatedData.StartDate.IsZero() {
+		reward.StartDate = updatedData.StartDate
+	}
+	if !updatedData.EndDate.IsZero() {
+		reward.EndDate = updatedData.EndDate
+		reward.IsActive = !rewardEnded(reward, time.Now())
+	}
+	if !validRewardWindow(reward) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end_date must be after start_date"})
+	}
+	if toggles.AutoExpireAfterRedemption != nil {
+		reward.AutoExpireAfterRedemption = *toggles.AutoExpireAfterRedemption
+	}
+	if toggles.IsActive != nil {
+		if *toggles.IsActive && rewardEnded(reward, time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot activate a reward whose campaign has ended"})
+		}
+		reward.IsActive = *toggles.IsActive
+	}
+	// Changing an approved reward sends it back for review and out of the catalog.
+	// Changing one under review puts it back at the end of the queue, so the
+	// admin never approves a version they did not see.
+	approvedOrQueued := reward.ApprovalStatus == models.RewardApproved || reward.ApprovalStatus == models.RewardSubmitted
+	if approvedOrQueued && rewardContentChanged(before, reward) {
+		now := time.Now()
+		reward.ApprovalStatus = models.RewardSubmitted
+		reward.SubmittedAt = &now
+	}
 	db.DB.Save(&reward)
 
 	return c.JSON(reward)
@@ -375,12 +694,7 @@ func PartnerUpdateReward(c *fiber.Ctx) error {
 // DeleteReward deletes a reward created by the logged-in partner
 func PartnerDeleteReward(c *fiber.Ctx) error {
 	//Get Logged-in Partner
-	role:=c.Locals("role").(string)
 	userID:= uint(c.Locals("user_id").(float64))
-	if role!="partner"{
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid Access"})
-	}
-
 	//Get Reward ID from URL
 	rewardID, err := c.ParamsInt("id")
 	if err != nil {
@@ -395,30 +709,36 @@ func PartnerDeleteReward(c *fiber.Ctx) error {
 	if reward.CreatedByID != userID {
 		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this reward"})
 	}
+	if hasPendingRedemptions(reward.ID) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Reward has pending redemptions, confirm or reject them first"})
+	}
 	// Delete the Rewar
 	db.DB.Delete(&reward)
 	return c.Status(200).JSON(fiber.Map{"message": "Reward deleted successfully"})
 }
 
-// GetPartnerRewards retrieves all rewards created by the logged-in partner
+// hasPendingRedemptions reports whether the reward still has holds awaiting the partner
+func hasPendingRedemptions(rewardID uint) bool {
+	var pending int64
+	db.DB.Model(&models.Transaction{}).Where("reward_id = ? AND status = ?", rewardID, models.StatusPending).Count(&pending)
+	return pending > 0
+}
+
+// GetPartnerRewards retrieves a page of the rewards created by the logged-in
+// partner, accepting the same filters and sort orders as the catalog plus
+// approval_status
 func GetPartnerRewards(c *fiber.Ctx) error {
-	role:=c.Locals("role").(string)
 	userID:= uint(c.Locals("user_id").(float64))
-	if role!="partner"{
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid Access"})
+	query := db.DB.Where("created_by_id = ?", userID)
+	if status := c.Query("approval_status"); status != "" {
+		query = query.Where("approval_status = ?", status)
 	}
-    var rewards []models.Reward
-    db.DB.Where("created_by_id = ?", userID).Find(&rewards)
-    return c.JSON(rewards)
+	return rewardPage(c, query)
 }
 
 // GetPartnerAnalytics retrieves analytics for the logged-in partner
 func GetPartnerAnalytics(c *fiber.Ctx) error {
-	role:=c.Locals("role").(string)
 	userID:= uint(c.Locals("user_id").(float64))
-	if role!="partner"{
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid Access"})
-	}
 	var rewards []models.Reward
 	db.DB.Where("created_by_id = ?", userID).Find(&rewards)
 	var totalRedemptions int64
@@ -456,5 +776,5 @@ func ViewProfile(c *fiber.Ctx) error {
 	userID:= uint(c.Locals("user_id").(float64))
 	var u models.User
 	db.DB.First(&u, userID)
-	return c.JSON(fiber.Map{"username" : u.Username, "points": u.Points})
+	return c.JSON(fiber.Map{"username" : u.Username, "points": u.Points, "tier": tiers.ByName(u.Tier), "referral_code": u.ReferralCode})
 }
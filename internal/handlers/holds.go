@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/ledger"
+	"authapi/internal/models"
+	"errors"
+	"log"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// holdTTL is how long a partner has to confirm a pending redemption before it is released
+const holdTTL = 48 * time.Hour
+
+var errNotPending = errors.New("transaction is not pending")
+
+// placeHold records a pending redemption, moving the reward's cost from the
+// user's wallet into the holds account. Stock must already be decremented.
+func placeHold(tx *gorm.DB, userID uint, reward models.Reward) (models.Transaction, error) {
+	expiresAt := time.Now().Add(holdTTL)
+	t := models.Transaction{
+		Type:          models.TransactionRedemption,
+		UserID:        userID,
+		RewardID:      reward.ID,
+		Status:        models.StatusPending,
+		PointsUsed:    reward.Cost,
+		HoldExpiresAt: &expiresAt,
+		CreatedAt:     time.Now(),
+	}
+	if err := tx.Create(&t).Error; err != nil {
+		return t, err
+	}
+	return t, ledger.Post(tx, ledger.Wallet(userID), ledger.Holds, reward.Cost, ledger.ReasonHold, &t.ID)
+}
+
+// lockPendingTransaction locks a pending redemption, its user and its reward. A non-zero
+// partnerID limits it to that partner's rewards. The returned reward is zero when
+// it has been deleted, which only a release without a partner tolerates.
+func lockPendingTransaction(tx *gorm.DB, transactionID, partnerID uint) (models.Transaction, models.Reward, error) {
+	var t models.Transaction
+	var reward models.Reward
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&t, transactionID).Error; err != nil {
+		return t, reward, errTransactionNotFound
+	}
+	if t.Status != models.StatusPending {
+		return t, reward, errNotPending
+	}
+	// Lock order matches redemption: user, then reward
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.User{}, t.UserID).Error; err != nil {
+		return t, reward, errUserNotFound
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, t.RewardID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return t, reward, err
+	}
+	// Without the reward a partner cannot show it was theirs
+	if partnerID != 0 && (reward.ID == 0 || reward.CreatedByID != partnerID) {
+		return t, reward, errNotRewardOwner
+	}
+	return t, reward, nil
+}
+
+// releaseHold returns a pending redemption's points to the user, with their
+// original expiry, and its stock to the reward if it still exists
+func releaseHold(tx *gorm.DB, t *models.Transaction, reward models.Reward, reason string) error {
+	if err := tx.Model(t).Updates(map[string]interface{}{
+		"status":         models.StatusReleased,
+		"coupon_status":  models.CouponVoid,
+		"release_reason": reason,
+	}).Error; err != nil {
+		return err
+	}
+	if reward.ID != 0 {
+		if err := tx.Model(&reward).UpdateColumn("stock", gorm.Expr("stock + 1")).Error; err != nil {
+			return err
+		}
+	}
+	return ledger.Post(tx, ledger.Holds, ledger.Wallet(t.UserID), t.PointsUsed, ledger.ReasonHoldRelease, &t.ID)
+}
+
+func holdResponse(c *fiber.Ctx, message string, t models.Transaction, err error) error {
+	switch {
+	case errors.Is(err, errTransactionNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Transaction not found"})
+	case errors.Is(err, errNotRewardOwner):
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this reward"})
+	case errors.Is(err, errNotPending):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Transaction is not pending"})
+	case errors.Is(err, errUserNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, errRewardNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Reward not found"})
+	case errors.Is(err, errRewardEnded):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Reward campaign has ended, reject the redemption instead"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update transaction"})
+	}
+	return c.JSON(fiber.Map{"message": message, "transaction": t})
+}
+
+// PartnerConfirmRedemption captures a pending redemption once the partner has fulfilled it
+func PartnerConfirmRedemption(c *fiber.Ctx) error {
+	partnerID := uint(c.Locals("user_id").(float64))
+	transactionID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid transaction ID"})
+	}
+	var t models.Transaction
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var reward models.Reward
+		var err error
+		t, reward, err = lockPendingTransaction(tx, uint(transactionID), partnerID)
+		if err != nil {
+			return err
+		}
+		// A coupon issued after the campaign ended could never be used
+		if rewardEnded(reward, time.Now()) {
+			return errRewardEnded
+		}
+		expiresAt := couponExpiry(reward, time.Now())
+		code, err := issueCoupon(tx, reward, func(tx *gorm.DB, code string) error {
+			return tx.Model(&t).Updates(map[string]interface{}{
+				"status":            models.StatusCompleted,
+				"coupon_status":     models.CouponActive,
+				"coupon_code":       code,
+				"coupon_expires_at": expiresAt,
+				"hold_expires_at":   nil,
+			}).Error
+		})
+		if err != nil {
+			return err
+		}
+		t.Status = models.StatusCompleted
+		t.CouponStatus = models.CouponActive
+		t.CouponCode = code
+		t.CouponExpiresAt = expiresAt
+		t.HoldExpiresAt = nil
+		return ledger.Post(tx, ledger.Holds, ledger.Redemptions, t.PointsUsed, ledger.ReasonHoldCapture, &t.ID)
+	})
+	return holdResponse(c, "Redemption confirmed", t, err)
+}
+
+// PartnerRejectRedemption releases a pending redemption the partner cannot fulfil
+func PartnerRejectRedemption(c *fiber.Ctx) error {
+	partnerID := uint(c.Locals("user_id").(float64))
+	transactionID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid transaction ID"})
+	}
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	var t models.Transaction
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var reward models.Reward
+		var err error
+		t, reward, err = lockPendingTransaction(tx, uint(transactionID), partnerID)
+		if err != nil {
+			return err
+		}
+		if err := releaseHold(tx, &t, reward, input.Reason); err != nil {
+			return err
+		}
+		return tx.First(&t, t.ID).Error
+	})
+	return holdResponse(c, "Redemption rejected", t, err)
+}
+
+// Release pending redemptions the partner did not confirm in time
+func ReleaseExpiredHolds() {
+	var due []models.Transaction
+	if err := db.DB.Where("status = ? AND hold_expires_at < ?", models.StatusPending, time.Now()).Find(&due).Error; err != nil {
+		log.Println("Hold release failed:", err)
+		return
+	}
+	released := 0
+	for _, d := range due {
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			t, reward, err := lockPendingTransaction(tx, d.ID, 0)
+			if err != nil {
+				return err
+			}
+			return releaseHold(tx, &t, reward, "hold expired")
+		})
+		if errors.Is(err, errNotPending) {
+			continue
+		}
+		if err != nil {
+			log.Println("Hold release failed for transaction", d.ID, ":", err)
+			continue
+		}
+		released++
+	}
+	if released > 0 {
+		log.Printf("Released %d expired holds\n", released)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"math"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// issueOTP sets a fresh hashed OTP on the user and returns the plaintext code to email
+func issueOTP(u *models.User) string {
+	otp := utils.GenerateOTP()
+	u.OTP = utils.HashOTP(otp)
+	u.OTPExpiresAt = time.Now().Add(utils.OTPTTL)
+	u.OTPSentAt = time.Now()
+	u.OTPAttempts = 0
+	return otp
+}
+
+// clearOTP consumes the user's current OTP so it cannot be used again
+func clearOTP(u *models.User) {
+	u.OTP = ""
+	u.OTPAttempts = 0
+}
+
+// takeOTPAttempt atomically counts a guess against the user's OTP. It reports
+// false once the attempt limit is reached, so parallel guesses cannot slip past it.
+func takeOTPAttempt(userID uint) bool {
+	result := db.DB.Model(&models.User{}).
+		Where("id = ? AND otp_attempts < ?", userID, utils.MaxOTPAttempts).
+		UpdateColumn("otp_attempts", gorm.Expr("otp_attempts + 1"))
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// ResendOTP sends a new verification OTP to an unverified user
+func ResendOTP(c *fiber.Ctx) error {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if input.Email == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Email required"})
+	}
+	var user models.User
+	if err := db.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	}
+	if user.IsVerified {
+		return c.Status(400).JSON(fiber.Map{"error": "Account already verified"})
+	}
+	if wait := utils.OTPResendCooldown - time.Since(user.OTPSentAt); wait > 0 {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":       "Please wait before requesting another OTP",
+			"retry_after": int(math.Ceil(wait.Seconds())),
+		})
+	}
+
+	otp := issueOTP(&user)
+	if err := db.DB.Save(&user).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save user"})
+	}
+	if err := utils.SendOTPEmail(user.Email, otp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send otp to user"})
+	}
+	return c.JSON(fiber.Map{"message": "OTP sent to email"})
+}
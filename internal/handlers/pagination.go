@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Page sizes for list endpoints
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Kinds of values a sort column holds, needed to decode cursors
+const (
+	sortInt    = "int"
+	sortString = "string"
+	sortTime   = "time"
+)
+
+// pageSort orders a list by one column with the row ID as tie-breaker, so a
+// cursor of (column value, ID) identifies a position that stays stable while
+// rows are added or removed
+type pageSort struct {
+	column string
+	kind   string
+	desc   bool
+}
+
+// pageCursor is the decoded form of the opaque next_cursor handed to clients
+type pageCursor struct {
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// pageLimit reads ?limit, clamped to the allowed page size
+func pageLimit(c *fiber.Ctx) int {
+	limit := c.QueryInt("limit", defaultPageSize)
+	if limit < 1 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	return limit
+}
+
+// paginate orders the query and, when a cursor is given, skips to the rows after it
+func (s pageSort) paginate(query *gorm.DB, cursor string, idColumn string) (*gorm.DB, error) {
+	direction, compare := "ASC", ">"
+	if s.desc {
+		direction, compare = "DESC", "<"
+	}
+	if s.column == idColumn {
+		query = query.Order(idColumn + " " + direction)
+	} else {
+		query = query.Order(s.column + " " + direction).Order(idColumn + " " + direction)
+	}
+	if cursor == "" {
+		return query, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var pc pageCursor
+	if err := json.Unmarshal(raw, &pc); err != nil {
+		return nil, errInvalidCursor
+	}
+	if s.column == idColumn {
+		return query.Where(fmt.Sprintf("%s %s ?", idColumn, compare), pc.ID), nil
+	}
+	var value interface{}
+	switch s.kind {
+	case sortInt:
+		var v int64
+		err = json.Unmarshal(pc.Value, &v)
+		value = v
+	case sortString:
+		var v string
+		err = json.Unmarshal(pc.Value, &v)
+		value = v
+	case sortTime:
+		var v time.Time
+		err = json.Unmarshal(pc.Value, &v)
+		value = v
+	}
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return query.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", s.column, idColumn, compare), value, pc.ID), nil
+}
+
+// encodeCursor builds the cursor pointing just after a row
+func encodeCursor(value interface{}, id uint) string {
+	raw, _ := json.Marshal(struct {
+		Value interface{} `json:"v"`
+		ID    uint        `json:"id"`
+	}{value, id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// pageEnvelope is the response shape shared by all paginated lists. next_cursor
+// is empty on the last page.
+func pageEnvelope(items interface{}, nextCursor string, total int64) fiber.Map {
+	return fiber.Map{
+		"items":       items,
+		"next_cursor": nextCursor,
+		"total":       total,
+	}
+}
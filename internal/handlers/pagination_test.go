@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"authapi/internal/models"
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB builds queries without a database so the generated SQL can be checked
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	database, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestPaginate(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		sort     pageSort
+		cursor   string
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:    "first page",
+			sort:    pageSort{column: "cost", kind: sortInt},
+			wantSQL: `SELECT * FROM "rewards" ORDER BY cost ASC,id ASC`,
+		},
+		{
+			name:    "by id",
+			sort:    pageSort{column: "id", kind: sortInt, desc: true},
+			cursor:  encodeCursor(42, 42),
+			wantSQL: `SELECT * FROM "rewards" WHERE id < $1 ORDER BY id DESC`,
+			wantVars: []interface{}{uint(42)},
+		},
+		{
+			name:     "int column ascending",
+			sort:     pageSort{column: "cost", kind: sortInt},
+			cursor:   encodeCursor(100, 7),
+			wantSQL:  `SELECT * FROM "rewards" WHERE (cost, id) > ($1, $2) ORDER BY cost ASC,id ASC`,
+			wantVars: []interface{}{int64(100), uint(7)},
+		},
+		{
+			name:     "string column descending",
+			sort:     pageSort{column: "name", kind: sortString, desc: true},
+			cursor:   encodeCursor("Movie Tickets", 3),
+			wantSQL:  `SELECT * FROM "rewards" WHERE (name, id) < ($1, $2) ORDER BY name DESC,id DESC`,
+			wantVars: []interface{}{"Movie Tickets", uint(3)},
+		},
+		{
+			name:     "time column",
+			sort:     pageSort{column: "created_at", kind: sortTime, desc: true},
+			cursor:   encodeCursor(at, 9),
+			wantSQL:  `SELECT * FROM "rewards" WHERE (created_at, id) < ($1, $2) ORDER BY created_at DESC,id DESC`,
+			wantVars: []interface{}{at, uint(9)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := tt.sort.paginate(dryRunDB(t).Model(&models.Reward{}), tt.cursor, "id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmt := query.Find(&[]models.Reward{}).Statement
+			if got := stmt.SQL.String(); got != tt.wantSQL {
+				t.Errorf("SQL =\n%s\nwant\n%s", got, tt.wantSQL)
+			}
+			if len(stmt.Vars) != len(tt.wantVars) || (len(tt.wantVars) > 0 && !reflect.DeepEqual(stmt.Vars, tt.wantVars)) {
+				t.Errorf("vars = %#v, want %#v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestPaginateInvalidCursor(t *testing.T) {
+	wrongKind := encodeCursor("cheap", 1)
+	for _, cursor := range []string{"not base64!", base64.RawURLEncoding.EncodeToString([]byte("{")), wrongKind} {
+		_, err := pageSort{column: "cost", kind: sortInt}.paginate(dryRunDB(t).Model(&models.Reward{}), cursor, "id")
+		if err != errInvalidCursor {
+			t.Errorf("paginate(%q) error = %v, want errInvalidCursor", cursor, err)
+		}
+	}
+}
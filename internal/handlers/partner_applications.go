@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// partnerInviteTTL is how long an approved partner has to set their password
+const partnerInviteTTL = 7 * 24 * time.Hour
+
+var (
+	errApplicationNotFound   = errors.New("application not found")
+	errApplicationNotPending = errors.New("application has already been reviewed")
+	errInviteNotPending      = errors.New("invite has already been accepted or the application was not approved")
+	errEmailRegistered       = errors.New("email already registered")
+)
+
+// partnerInviteLink points at the frontend page where the invite token is redeemed
+func partnerInviteLink(token string) string {
+	base := os.Getenv("FRONTEND_URL")
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return strings.TrimRight(base, "/") + "/partner/invite?token=" + token
+}
+
+// issuePartnerInvite stores a fresh invite token for the application and returns it
+func issuePartnerInvite(tx *gorm.DB, application *models.PartnerApplication) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().Add(partnerInviteTTL)
+	application.InviteTokenHash = utils.HashToken(token)
+	application.InviteExpiresAt = &expiresAt
+	return token, tx.Model(application).Updates(map[string]interface{}{
+		"invite_token_hash": application.InviteTokenHash,
+		"invite_expires_at": expiresAt,
+	}).Error
+}
+
+func sendPartnerInvite(application models.PartnerApplication, token string) {
+	go func() {
+		if err := utils.SendPartnerInviteEmail(application.Email, application.CompanyName, partnerInviteLink(token)); err != nil {
+			log.Println("Could not send partner invite:", err)
+		}
+	}()
+}
+
+// ApplyForPartnership records a business's application to become a partner
+func ApplyForPartnership(c *fiber.Ctx) error {
+	var application models.PartnerApplication
+	if err := c.BodyParser(&application); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	application.CompanyName = strings.TrimSpace(application.CompanyName)
+	application.ContactName = strings.TrimSpace(application.ContactName)
+	application.Email = strings.ToLower(strings.TrimSpace(application.Email))
+	if application.CompanyName == "" || application.ContactName == "" || !strings.Contains(application.Email, "@") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Company name, contact name and a valid email are required"})
+	}
+	// Review fields are only ever set by admins
+	application.ID = 0
+	application.Status = models.ApplicationPending
+	application.RejectionReason = ""
+	application.ReviewedByID = 0
+	application.ReviewedAt = nil
+	application.PartnerID = 0
+	application.InviteTokenHash = ""
+	application.InviteExpiresAt = nil
+	application.InviteAcceptedAt = nil
+
+	var existing int64
+	db.DB.Model(&models.User{}).Where("LOWER(email) = ?", application.Email).Count(&existing)
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already Registered"})
+	}
+	db.DB.Model(&models.PartnerApplication{}).Where("email = ? AND status = ?", application.Email, models.ApplicationPending).Count(&existing)
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "An application for this email is already under review"})
+	}
+	// A concurrent application for the same email trips the unique index on pending emails
+	if err := db.DB.Create(&application).Error; errors.Is(err, gorm.ErrDuplicatedKey) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "An application for this email is already under review"})
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not submit application"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Application submitted. We will email you once it has been reviewed", "application_id": application.ID})
+}
+
+// AdminListPartnerApplications pages through applications by status, pending ones by default
+func AdminListPartnerApplications(c *fiber.Ctx) error {
+	base := db.DB.Model(&models.PartnerApplication{}).
+		Where("status = ?", c.Query("status", models.ApplicationPending)).
+		Session(&gorm.Session{})
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch applications"})
+	}
+	// Oldest first, so the queue is worked in arrival order
+	paged, err := pageSort{column: "id"}.paginate(base, c.Query("cursor"), "id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cursor"})
+	}
+	limit := pageLimit(c)
+	applications := []models.PartnerApplication{}
+	if err := paged.Limit(limit + 1).Find(&applications).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch applications"})
+	}
+	next := ""
+	if len(applications) > limit {
+		applications = applications[:limit]
+		next = encodeCursor(nil, applications[limit-1].ID)
+	}
+	return c.JSON(pageEnvelope(applications, next, total))
+}
+
+// lockPendingApplication loads an application that is still awaiting review, locked for update
+func lockPendingApplication(tx *gorm.DB, id int) (models.PartnerApplication, error) {
+	var application models.PartnerApplication
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&application, id).Error; err != nil {
+		return application, errApplicationNotFound
+	}
+	if application.Status != models.ApplicationPending {
+		return application, errApplicationNotPending
+	}
+	return application, nil
+}
+
+func applicationResponse(c *fiber.Ctx, message string, application models.PartnerApplication, err error) error {
+	switch {
+	case errors.Is(err, errApplicationNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Application not found"})
+	case errors.Is(err, errApplicationNotPending):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": errApplicationNotPending.Error()})
+	case errors.Is(err, errInviteNotPending):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": errInviteNotPending.Error()})
+	case errors.Is(err, errEmailRegistered):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already Registered"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update application"})
+	}
+	return c.JSON(fiber.Map{"message": message, "application": application})
+}
+
+// createPartnerAccount inserts the user row of an approved partner. A signup
+// racing the approval can take the email, or the generated referral code,
+// between the checks and the insert. Only a taken email is reported as
+// errEmailRegistered; a referral code clash is retried with a fresh code.
+func createPartnerAccount(tx *gorm.DB, partner *models.User) error {
+	for attempt := 0; attempt < 5; attempt++ {
+		if err := assignReferralCode(tx, partner); err != nil {
+			return err
+		}
+		err := tx.Transaction(func(sp *gorm.DB) error {
+			return sp.Create(partner).Error
+		})
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+		var taken int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("LOWER(email) = ?", partner.Email).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return errEmailRegistered
+		}
+	}
+	return errors.New("could not generate a unique referral code")
+}
+
+// AdminApprovePartnerApplication creates the partner account for an application
+// and emails the applicant an invite to set its password. The account has no
+// usable password until the invite is accepted.
+func AdminApprovePartnerApplication(c *fiber.Ctx) error {
+	adminID := uint(c.Locals("user_id").(float64))
+	applicationID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid application ID"})
+	}
+	var application models.PartnerApplication
+	var token string
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		application, err = lockPendingApplication(tx, applicationID)
+		if err != nil {
+			return err
+		}
+		var existing int64
+		tx.Model(&models.User{}).Where("LOWER(email) = ?", application.Email).Count(&existing)
+		if existing > 0 {
+			return errEmailRegistered
+		}
+		partner := models.User{
+			Username:   application.CompanyName,
+			Email:      application.Email,
+			Role:       "partner",
+			IsVerified: true,
+		}
+		if err := createPartnerAccount(tx, &partner); err != nil {
+			return err
+		}
+		now := time.Now()
+		application.Status = models.ApplicationApproved
+		application.ReviewedByID = adminID
+		application.ReviewedAt = &now
+		application.PartnerID = partner.ID
+		if err := tx.Model(&application).Updates(map[string]interface{}{
+			"status":         application.Status,
+			"reviewed_by_id": adminID,
+			"reviewed_at":    now,
+			"partner_id":     partner.ID,
+		}).Error; err != nil {
+			return err
+		}
+		token, err = issuePartnerInvite(tx, &application)
+		return err
+	})
+	if err == nil {
+		sendPartnerInvite(application, token)
+	}
+	return applicationResponse(c, "Application approved, invite sent", application, err)
+}
+
+// AdminRejectPartnerApplication declines an application and tells the applicant why
+func AdminRejectPartnerApplication(c *fiber.Ctx) error {
+	adminID := uint(c.Locals("user_id").(float64))
+	applicationID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid application ID"})
+	}
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A rejection reason is required"})
+	}
+	var application models.PartnerApplication
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		application, err = lockPendingApplication(tx, applicationID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		application.Status = models.ApplicationRejected
+		application.RejectionReason = input.Reason
+		application.ReviewedByID = adminID
+		application.ReviewedAt = &now
+		return tx.Model(&application).Updates(map[string]interface{}{
+			"status":           application.Status,
+			"rejection_reason": input.Reason,
+			"reviewed_by_id":   adminID,
+			"reviewed_at":      now,
+		}).Error
+	})
+	if err == nil {
+		go func(application models.PartnerApplication) {
+			if err := utils.SendPartnerRejectedEmail(application.Email, application.CompanyName, application.RejectionReason); err != nil {
+				log.Println("Could not send partner rejection email:", err)
+			}
+		}(application)
+	}
+	return applicationResponse(c, "Application rejected", application, err)
+}
+
+// AdminResendPartnerInvite replaces the invite of an approved partner who has not set a password yet
+func AdminResendPartnerInvite(c *fiber.Ctx) error {
+	applicationID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid application ID"})
+	}
+	var application models.PartnerApplication
+	var token string
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&application, applicationID).Error; err != nil {
+			return errApplicationNotFound
+		}
+		if application.Status != models.ApplicationApproved || application.InviteAcceptedAt != nil {
+			return errInviteNotPending
+		}
+		var err error
+		token, err = issuePartnerInvite(tx, &application)
+		return err
+	})
+	if err == nil {
+		sendPartnerInvite(application, token)
+	}
+	return applicationResponse(c, "Invite sent", application, err)
+}
+
+// AcceptPartnerInvite sets the password of a newly approved partner account
+func AcceptPartnerInvite(c *fiber.Ctx) error {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if input.Token == "" || input.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token and password required"})
+	}
+	hashedPassword, err := utils.HashingPassword(input.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var application models.PartnerApplication
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("invite_token_hash = ? AND invite_accepted_at IS NULL", utils.HashToken(input.Token)).
+			First(&application).Error; err != nil {
+			return errInviteNotPending
+		}
+		if application.InviteExpiresAt == nil || time.Now().After(*application.InviteExpiresAt) {
+			return errInviteNotPending
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", application.PartnerID).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return tx.Model(&application).Updates(map[string]interface{}{
+			"invite_accepted_at": time.Now(),
+			"invite_token_hash":  "",
+		}).Error
+	})
+	if errors.Is(err, errInviteNotPending) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired invite"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not activate partner account"})
+	}
+	return c.JSON(fiber.Map{"message": "Password set, you can now log in"})
+}
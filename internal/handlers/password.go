@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"log"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ForgotPassword emails a reset OTP to the account, if one exists. The response
+// is the same either way so the endpoint cannot be used to probe for emails.
+func ForgotPassword(c *fiber.Ctx) error {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if input.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email required"})
+	}
+	response := fiber.Map{"message": "If the email is registered, an OTP has been sent"}
+
+	var user models.User
+	if err := db.DB.Where("email = ? AND is_verified = ?", input.Email, true).First(&user).Error; err != nil {
+		return c.JSON(response)
+	}
+	if time.Since(user.OTPSentAt) < utils.OTPResendCooldown {
+		return c.JSON(response)
+	}
+	otp := issueOTP(&user)
+	if err := db.DB.Save(&user).Error; err != nil {
+		log.Println("Could not store reset OTP:", err)
+		return c.JSON(response)
+	}
+	// Sent in the background so response time does not reveal whether the account exists
+	go func(email, otp string) {
+		if err := utils.SendOTPEmail(email, otp); err != nil {
+			log.Println("Could not send reset OTP:", err)
+		}
+	}(user.Email, otp)
+	return c.JSON(response)
+}
+
+// ResetPassword sets a new password using an emailed OTP and signs the user out everywhere
+func ResetPassword(c *fiber.Ctx) error {
+	var input struct {
+		Email       string `json:"email"`
+		OTP         string `json:"otp"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if input.Email == "" || input.OTP == "" || input.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email, OTP and new password required"})
+	}
+
+	var user models.User
+	if err := db.DB.Where("email = ? AND is_verified = ?", input.Email, true).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired OTP"})
+	}
+	if !takeOTPAttempt(user.ID) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many attempts, request a new OTP"})
+	}
+	if time.Now().After(user.OTPExpiresAt) || !utils.CheckOTP(input.OTP, user.OTP) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired OTP"})
+	}
+
+	hashedPassword, err := utils.HashingPassword(input.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		user.Password = hashedPassword
+		clearOTP(&user)
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset password"})
+	}
+	return c.JSON(fiber.Map{"message": "Password reset successful"})
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/ledger"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var connectOnce sync.Once
+
+// connectTestDB points db.DB at the Postgres database named by TEST_DATABASE_DSN,
+// running the usual migrations. Tests needing a database are skipped without it.
+func connectTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+	connectOnce.Do(func() {
+		os.Setenv("DB_DSN", dsn)
+		db.Connect()
+	})
+}
+
+// createTestUser makes a verified user holding the given points
+func createTestUser(t *testing.T, name string, points int) models.User {
+	t.Helper()
+	u := models.User{
+		Username:     name,
+		Email:        name + "@test.local",
+		Role:         "user",
+		IsVerified:   true,
+		ReferralCode: utils.GenerateReferralCode(),
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&u).Error; err != nil {
+			return err
+		}
+		return ledger.Credit(tx, u.ID, points, ledger.ReasonOpeningBalance, nil)
+	})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return u
+}
+
+// redeemConcurrently fires attempts parallel redemptions of the reward for each
+// user, released together, and counts the response statuses
+func redeemConcurrently(t *testing.T, rewardID uint, userIDs []uint, attempts int) map[int]int {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/redeem", func(c *fiber.Ctx) error {
+		id, _ := strconv.Atoi(c.Get("X-Test-User"))
+		c.Locals("user_id", float64(id))
+		return c.Next()
+	}, RedeemReward)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	statuses := map[int]int{}
+	start := make(chan struct{})
+	for _, id := range userIDs {
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func(id uint) {
+				defer wg.Done()
+				<-start
+				req := httptest.NewRequest(http.MethodPost, "/redeem", strings.NewReader(fmt.Sprintf(`{"reward_id":%d}`, rewardID)))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("X-Test-User", strconv.Itoa(int(id)))
+				resp, err := app.Test(req, -1)
+				if err != nil {
+					t.Errorf("request: %v", err)
+					return
+				}
+				mu.Lock()
+				statuses[resp.StatusCode]++
+				mu.Unlock()
+			}(id)
+		}
+	}
+	close(start)
+	wg.Wait()
+	return statuses
+}
+
+// checkLedger asserts that every journal balances and that each user's cached
+// points agree with their ledger balance and their unspent lots
+func checkLedger(t *testing.T, userIDs []uint) {
+	t.Helper()
+	var unbalanced int64
+	db.DB.Raw(`SELECT COUNT(*) FROM (SELECT journal_id FROM ledger_entries GROUP BY journal_id HAVING SUM(credit) <> SUM(debit)) j`).Scan(&unbalanced)
+	if unbalanced != 0 {
+		t.Errorf("%d unbalanced journals", unbalanced)
+	}
+	for _, id := range userIDs {
+		var u models.User
+		db.DB.First(&u, id)
+		balance, err := ledger.Balance(db.DB, id)
+		if err != nil {
+			t.Fatalf("balance: %v", err)
+		}
+		var inLots int
+		db.DB.Model(&models.PointLot{}).Select("COALESCE(SUM(remaining), 0)").Where("user_id = ? AND expired_at IS NULL", id).Scan(&inLots)
+		if u.Points < 0 || u.Points != balance || u.Points != inLots {
+			t.Errorf("user %d cached points %d, ledger balance %d, lots %d", id, u.Points, balance, inLots)
+		}
+	}
+}
+
+func TestRedeemRewardConcurrentNoOversell(t *testing.T) {
+	connectTestDB(t)
+	const stock = 10
+	const cost = 25
+	const users = 5
+	const attemptsPerUser = 10
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	reward := models.Reward{Name: "Concurrency " + suffix, Cost: cost, Stock: stock, IsActive: true, ApprovalStatus: models.RewardApproved}
+	if err := db.DB.Create(&reward).Error; err != nil {
+		t.Fatalf("create reward: %v", err)
+	}
+	var userIDs []uint
+	for i := 0; i < users; i++ {
+		u := createTestUser(t, fmt.Sprintf("redeem-%s-%d", suffix, i), cost*attemptsPerUser)
+		userIDs = append(userIDs, u.ID)
+	}
+
+	statuses := redeemConcurrently(t, reward.ID, userIDs, attemptsPerUser)
+
+	if statuses[fiber.StatusOK] != stock {
+		t.Fatalf("successful redemptions = %d, want %d (statuses %v)", statuses[fiber.StatusOK], stock, statuses)
+	}
+	if statuses[fiber.StatusOK]+statuses[fiber.StatusBadRequest] != users*attemptsPerUser {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+
+	if err := db.DB.First(&reward, reward.ID).Error; err != nil {
+		t.Fatalf("reload reward: %v", err)
+	}
+	if reward.Stock != 0 {
+		t.Errorf("stock = %d, want 0", reward.Stock)
+	}
+	var redemptions int64
+	db.DB.Model(&models.Transaction{}).Where("reward_id = ?", reward.ID).Count(&redemptions)
+	if redemptions != stock {
+		t.Errorf("transactions = %d, want %d", redemptions, stock)
+	}
+
+	checkLedger(t, userIDs)
+	// Spent points match the redemptions
+	var spent int
+	db.DB.Model(&models.LedgerEntry{}).
+		Select("COALESCE(SUM(debit), 0)").
+		Where("account = ? AND user_id IN ? AND reason = ?", "wallet", userIDs, ledger.ReasonRedemption).
+		Scan(&spent)
+	if spent != stock*cost {
+		t.Errorf("points spent = %d, want %d", spent, stock*cost)
+	}
+}
+
+func TestRedeemRewardConcurrentInsufficientPoints(t *testing.T) {
+	connectTestDB(t)
+	const cost = 25
+	const attempts = 20
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	reward := models.Reward{Name: "Overspend " + suffix, Cost: cost, Stock: attempts, IsActive: true, ApprovalStatus: models.RewardApproved}
+	if err := db.DB.Create(&reward).Error; err != nil {
+		t.Fatalf("create reward: %v", err)
+	}
+	// Enough points for exactly one redemption
+	u := createTestUser(t, "overspend-"+suffix, cost)
+
+	statuses := redeemConcurrently(t, reward.ID, []uint{u.ID}, attempts)
+	if statuses[fiber.StatusOK] != 1 || statuses[fiber.StatusBadRequest] != attempts-1 {
+		t.Fatalf("statuses %v, want 1 success and %d rejections", statuses, attempts-1)
+	}
+
+	var redemptions int64
+	db.DB.Model(&models.Transaction{}).Where("reward_id = ?", reward.ID).Count(&redemptions)
+	if redemptions != 1 {
+		t.Errorf("transactions = %d, want 1", redemptions)
+	}
+	if err := db.DB.First(&reward, reward.ID).Error; err != nil {
+		t.Fatalf("reload reward: %v", err)
+	}
+	if reward.Stock != attempts-1 {
+		t.Errorf("stock = %d, want %d", reward.Stock, attempts-1)
+	}
+	db.DB.First(&u, u.ID)
+	if u.Points != 0 {
+		t.Errorf("points = %d, want 0", u.Points)
+	}
+	checkLedger(t, []uint{u.ID})
+}
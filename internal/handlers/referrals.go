@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/ledger"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"errors"
+	"log"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	referrerBonusPoints = 200
+	refereeBonusPoints  = 100
+	// maxReferralsPerMonth caps how many referrals one user can be credited for in 30 days
+	maxReferralsPerMonth = 20
+	// maxReferralsPerIP caps credited referrals signing up from one IP address in 30 days
+	maxReferralsPerIP = 3
+)
+
+var errInvalidReferralCode = errors.New("invalid referral code")
+
+// assignReferralCode gives the user a referral code no other user holds
+func assignReferralCode(tx *gorm.DB, u *models.User) error {
+	for i := 0; i < 5; i++ {
+		code := utils.GenerateReferralCode()
+		var taken int64
+		if err := tx.Model(&models.User{}).Where("referral_code = ?", code).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken == 0 {
+			u.ReferralCode = code
+			return nil
+		}
+	}
+	return errors.New("could not generate a unique referral code")
+}
+
+// recordReferral links a newly registered user to the owner of the referral code
+func recordReferral(tx *gorm.DB, referee models.User, code, signupIP string) error {
+	var referrer models.User
+	if err := tx.Where("referral_code = ? AND is_verified = ?", code, true).First(&referrer).Error; err != nil {
+		return errInvalidReferralCode
+	}
+	referral := models.Referral{
+		ReferrerID: referrer.ID,
+		RefereeID:  referee.ID,
+		Status:     models.ReferralPending,
+		SignupIP:   signupIP,
+	}
+	return tx.Create(&referral).Error
+}
+
+// completeReferral credits both sides of the referee's pending referral, or
+// rejects it if it looks like self-referral or multi-account abuse
+func completeReferral(tx *gorm.DB, referee models.User) error {
+	var referral models.Referral
+	if err := tx.Where("referee_id = ? AND status = ?", referee.ID, models.ReferralPending).First(&referral).Error; err != nil {
+		return nil
+	}
+	var referrer models.User
+	if err := tx.First(&referrer, referral.ReferrerID).Error; err != nil {
+		return rejectReferral(tx, referral, "referrer no longer exists")
+	}
+	if referrer.ID == referee.ID || utils.NormalizeEmail(referrer.Email) == utils.NormalizeEmail(referee.Email) {
+		return rejectReferral(tx, referral, "self-referral")
+	}
+
+	since := time.Now().AddDate(0, 0, -30)
+	var referrerCount int64
+	if err := tx.Model(&models.Referral{}).
+		Where("referrer_id = ? AND status = ? AND credited_at >= ?", referrer.ID, models.ReferralCredited, since).
+		Count(&referrerCount).Error; err != nil {
+		return err
+	}
+	if referrerCount >= maxReferralsPerMonth {
+		return rejectReferral(tx, referral, "referrer monthly limit reached")
+	}
+	if referral.SignupIP != "" {
+		var ipCount int64
+		if err := tx.Model(&models.Referral{}).
+			Where("signup_ip = ? AND status = ? AND credited_at >= ?", referral.SignupIP, models.ReferralCredited, since).
+			Count(&ipCount).Error; err != nil {
+			return err
+		}
+		if ipCount >= maxReferralsPerIP {
+			return rejectReferral(tx, referral, "too many referrals from the same network")
+		}
+	}
+
+	now := time.Now()
+	if err := tx.Model(&referral).Updates(map[string]interface{}{"status": models.ReferralCredited, "credited_at": now}).Error; err != nil {
+		return err
+	}
+	if err := ledger.Credit(tx, referrer.ID, referrerBonusPoints, ledger.ReasonReferral, nil); err != nil {
+		return err
+	}
+	return ledger.Credit(tx, referee.ID, refereeBonusPoints, ledger.ReasonReferral, nil)
+}
+
+func rejectReferral(tx *gorm.DB, referral models.Referral, reason string) error {
+	log.Printf("Referral %d rejected: %s\n", referral.ID, reason)
+	return tx.Model(&referral).Updates(map[string]interface{}{"status": models.ReferralRejected, "reject_reason": reason}).Error
+}
+
+// BackfillReferralCodes gives every user created before referrals a code
+func BackfillReferralCodes() {
+	var users []models.User
+	db.DB.Where("referral_code IS NULL OR referral_code = ''").Find(&users)
+	for i := range users {
+		if err := assignReferralCode(db.DB, &users[i]); err != nil {
+			log.Println("Referral code backfill failed:", err)
+			return
+		}
+		db.DB.Model(&users[i]).UpdateColumn("referral_code", users[i].ReferralCode)
+	}
+}
+
+// GetTopReferrers lists the users with the most credited referrals
+func GetTopReferrers(c *fiber.Ctx) error {
+	type TopReferrer struct {
+		UserID    uint   `json:"user_id"`
+		Username  string `json:"username"`
+		Email     string `json:"email"`
+		Referrals int    `json:"referrals"`
+		Rejected  int    `json:"rejected"`
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	var top []TopReferrer
+	err := db.DB.Model(&models.Referral{}).
+		Select(`users.id AS user_id, users.username, users.email,
+			COUNT(*) FILTER (WHERE referrals.status = ?) AS referrals,
+			COUNT(*) FILTER (WHERE referrals.status = ?) AS rejected`, models.ReferralCredited, models.ReferralRejected).
+		Joins("JOIN users ON users.id = referrals.referrer_id").
+		Group("users.id, users.username, users.email").
+		Order("referrals DESC").
+		Limit(limit).
+		Scan(&top).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not fetch referrers"})
+	}
+	return c.JSON(top)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/ledger"
+	"authapi/internal/models"
+	"errors"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errTransactionNotFound = errors.New("transaction not found")
+	errNotRefundable       = errors.New("transaction cannot be refunded")
+	errNotRewardOwner      = errors.New("reward not owned by partner")
+)
+
+// refundTransaction reverses a completed redemption: the transaction moves to
+// Refunded, the coupon is voided, and the points and stock are restored, all in
+// one database transaction. Redemptions of deleted rewards still get their
+// points back. A non-zero partnerID limits the refund to redemptions of that
+// partner's rewards.
+func refundTransaction(transactionID int, actorID, partnerID uint, reason string) (models.Transaction, error) {
+	var t models.Transaction
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&t, transactionID).Error; err != nil {
+			return errTransactionNotFound
+		}
+		// A coupon that has already been used at the partner cannot be refunded
+		if t.Type != models.TransactionRedemption || t.Status != models.StatusCompleted || t.CouponStatus == models.CouponConsumed {
+			return errNotRefundable
+		}
+		// Lock order matches redemption: user, then reward
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, t.UserID).Error; err != nil {
+			return errUserNotFound
+		}
+		// The reward may have been deleted since; only the stock restore needs it
+		var reward models.Reward
+		rewardExists := true
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, t.RewardID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			rewardExists = false
+		} else if err != nil {
+			return err
+		}
+		// Without the reward a partner cannot show it was theirs, so only admins refund those
+		if partnerID != 0 && (!rewardExists || reward.CreatedByID != partnerID) {
+			return errNotRewardOwner
+		}
+
+		now := time.Now()
+		if err := tx.Model(&t).Updates(map[string]interface{}{
+			"status":         models.StatusRefunded,
+			"coupon_status":  models.CouponVoid,
+			"refunded_by_id": actorID,
+			"refund_reason":  reason,
+			"refunded_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+		// A handed-out voucher code cannot go back into inventory
+		if rewardExists && !reward.ExternalVouchers {
+			if err := tx.Model(&reward).UpdateColumn("stock", gorm.Expr("stock + 1")).Error; err != nil {
+				return err
+			}
+		}
+		if t.PointsUsed == 0 {
+			return nil
+		}
+		return ledger.Post(tx, ledger.Redemptions, ledger.Wallet(user.ID), t.PointsUsed, ledger.ReasonRefund, &t.ID)
+	})
+	return t, err
+}
+
+func refundResponse(c *fiber.Ctx, t models.Transaction, err error) error {
+	switch {
+	case errors.Is(err, errTransactionNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Transaction not found"})
+	case errors.Is(err, errNotRewardOwner):
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this reward"})
+	case errors.Is(err, errNotRefundable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only completed, unused redemptions can be refunded"})
+	case errors.Is(err, errUserNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, errRewardNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Reward not found"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not refund transaction"})
+	}
+	return c.JSON(fiber.Map{"message": "Transaction refunded", "transaction": t})
+}
+
+func parseRefundRequest(c *fiber.Ctx) (int, string, error) {
+	transactionID, err := c.ParamsInt("id")
+	if err != nil {
+		return 0, "", err
+	}
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return 0, "", err
+	}
+	if input.Reason == "" {
+		return 0, "", errors.New("reason required")
+	}
+	return transactionID, input.Reason, nil
+}
+
+// AdminRefundTransaction reverses any completed redemption
+func AdminRefundTransaction(c *fiber.Ctx) error {
+	adminID := uint(c.Locals("user_id").(float64))
+	transactionID, reason, err := parseRefundRequest(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Transaction ID and reason required"})
+	}
+	t, err := refundTransaction(transactionID, adminID, 0, reason)
+	return refundResponse(c, t, err)
+}
+
+// PartnerRefundTransaction reverses a completed redemption of one of the partner's rewards
+func PartnerRefundTransaction(c *fiber.Ctx) error {
+	partnerID := uint(c.Locals("user_id").(float64))
+	transactionID, reason, err := parseRefundRequest(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Transaction ID and reason required"})
+	}
+	t, err := refundTransaction(transactionID, partnerID, partnerID, reason)
+	return refundResponse(c, t, err)
+}
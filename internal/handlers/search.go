@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"strings"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Search modes, tried in order until one matches. "all" requires every term,
+// "any" accepts rewards matching some of them, "fuzzy" tolerates typos through
+// trigram similarity and "basic" is the substring match used when the
+// database supports neither.
+const (
+	searchAll   = "all"
+	searchAny   = "any"
+	searchFuzzy = "fuzzy"
+	searchBasic = "basic"
+)
+
+// fuzzyThreshold is the minimum word similarity for a fuzzy match. It is set as
+// pg_trgm.word_similarity_threshold so the <% operator can use the trigram indexes.
+const fuzzyThreshold = "0.3"
+
+// anyTermQuery ORs the lexemes of the terms kept by anyTerms. plainto_tsquery
+// only ever joins lexemes with &, so swapping those for | is safe.
+const (
+	allTermsQuery = "websearch_to_tsquery('english', ?)"
+	anyTermQuery  = "replace(plainto_tsquery('english', ?)::text, ' & ', ' | ')::tsquery"
+)
+
+// ts_headline marks matches with these private-use characters rather than
+// HTML, so the text can be escaped before the real tags go in
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+// costBuckets groups rewards by cost for the facet counts
+const costBuckets = `CASE
+	WHEN cost < 100 THEN '0-99'
+	WHEN cost < 250 THEN '100-249'
+	WHEN cost < 500 THEN '250-499'
+	WHEN cost < 1000 THEN '500-999'
+	ELSE '1000+' END`
+
+// rewardHit is a search result with its relevance and highlighted fields. The
+// highlights are safe HTML: the reward text is escaped and matches are wrapped
+// in <mark> tags.
+type rewardHit struct {
+	models.Reward
+	Rank                 float64 `json:"rank"`
+	NameHighlight        string  `json:"name_highlight,omitempty"`
+	DescriptionHighlight string  `json:"description_highlight,omitempty"`
+}
+
+type facetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// searchCursor continues a search in the same mode; results are ranked, so
+// pages are addressed by offset
+type searchCursor struct {
+	Mode   string `json:"m"`
+	Offset int    `json:"o"`
+}
+
+// searchModes lists the modes available on this database, in the order they are tried
+func searchModes() []string {
+	var modes []string
+	if db.FullTextSearch {
+		modes = append(modes, searchAll, searchAny)
+	}
+	if db.TrigramSearch {
+		modes = append(modes, searchFuzzy)
+	}
+	if len(modes) == 0 {
+		modes = append(modes, searchBasic)
+	}
+	return modes
+}
+
+func validSearchMode(mode string) bool {
+	for _, m := range searchModes() {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// anyTerms returns the words of a web search query that "any" mode matches on.
+// Quotes and the or keyword are dropped, and so are negated words and phrases,
+// which as alternatives would match nearly every reward.
+func anyTerms(q string) string {
+	var terms []string
+	parts := strings.Split(q, `"`)
+	for i, part := range parts {
+		// Odd parts are quoted phrases, negated when a '-' comes right before them
+		if i%2 == 1 {
+			if !strings.HasSuffix(parts[i-1], "-") {
+				terms = append(terms, part)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			if strings.HasPrefix(word, "-") || strings.EqualFold(word, "or") {
+				continue
+			}
+			terms = append(terms, word)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlightHTML escapes a ts_headline result and turns its match markers into <mark> tags
+func highlightHTML(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightStart, "<mark>")
+	return strings.ReplaceAll(s, highlightStop, "</mark>")
+}
+
+// matchRewards restricts the query to rewards matching q in the given mode
+func matchRewards(query *gorm.DB, mode, q string) *gorm.DB {
+	switch mode {
+	case searchAll:
+		return query.Where("search_vector @@ "+allTermsQuery, q)
+	case searchAny:
+		return query.Where("search_vector @@ "+anyTermQuery, anyTerms(q))
+	case searchFuzzy:
+		return query.Where("? <% name OR ? <% category", q, q)
+	}
+	pattern := likePattern(q)
+	return query.Where("name ILIKE ? OR description ILIKE ?", pattern, pattern)
+}
+
+// selectHits adds the rank and, for full-text modes, the highlighted name and description
+func selectHits(query *gorm.DB, mode, q string) *gorm.DB {
+	switch mode {
+	case searchAll, searchAny:
+		tsQuery := allTermsQuery
+		if mode == searchAny {
+			tsQuery = anyTermQuery
+			q = anyTerms(q)
+		}
+		markers := "StartSel=" + highlightStart + ", StopSel=" + highlightStop
+		return query.Select(
+			"rewards.*, ts_rank_cd(search_vector, "+tsQuery+") AS rank, "+
+				"ts_headline('english', name, "+tsQuery+", ?) AS name_highlight, "+
+				"ts_headline('english', description, "+tsQuery+", ?) AS description_highlight",
+			q, q, "HighlightAll=true, "+markers, q, "MaxFragments=2, "+markers)
+	case searchFuzzy:
+		return query.Select("rewards.*, GREATEST(word_similarity(?, name), word_similarity(?, category)) AS rank", q, q)
+	}
+	return query.Select("rewards.*, 0 AS rank")
+}
+
+// SearchRewards runs a ranked search over the catalog. Name matches weigh more
+// than category matches, which weigh more than the description. When no reward
+// contains every term the search widens to any term and then to typo-tolerant
+// matching. The response carries highlights and facet counts by category and
+// cost bucket, and accepts the catalog filters.
+func SearchRewards(c *fiber.Ctx) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "q is required"})
+	}
+	// The threshold is set per transaction, so every query of the search shares its connection
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if db.TrigramSearch {
+			if err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)", fuzzyThreshold).Error; err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Search failed"})
+			}
+		}
+		return searchRewards(c, tx, q)
+	})
+}
+
+// searchRewards runs SearchRewards on tx
+func searchRewards(c *fiber.Ctx, tx *gorm.DB, q string) error {
+	filtered, err := filterRewards(c, catalogRewards(tx, c))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	filtered = filtered.Model(&models.Reward{}).Session(&gorm.Session{})
+
+	var cursor searchCursor
+	if raw := c.Query("cursor"); raw != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(raw)
+		if err != nil || json.Unmarshal(decoded, &cursor) != nil || cursor.Offset < 0 || !validSearchMode(cursor.Mode) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cursor"})
+		}
+	}
+
+	var matched *gorm.DB
+	var total int64
+	mode := cursor.Mode
+	if mode != "" {
+		matched = matchRewards(filtered, mode, q).Session(&gorm.Session{})
+		if err := matched.Count(&total).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Search failed"})
+		}
+	} else {
+		for _, mode = range searchModes() {
+			matched = matchRewards(filtered, mode, q).Session(&gorm.Session{})
+			if err := matched.Count(&total).Error; err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Search failed"})
+			}
+			if total > 0 {
+				break
+			}
+		}
+	}
+
+	limit := pageLimit(c)
+	hits := []rewardHit{}
+	if err := selectHits(matched, mode, q).
+		Order("rank DESC").Order("id").
+		Offset(cursor.Offset).Limit(limit + 1).
+		Scan(&hits).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Search failed"})
+	}
+	for i := range hits {
+		hits[i].NameHighlight = highlightHTML(hits[i].NameHighlight)
+		hits[i].DescriptionHighlight = highlightHTML(hits[i].DescriptionHighlight)
+	}
+	next := ""
+	if len(hits) > limit {
+		hits = hits[:limit]
+		raw, _ := json.Marshal(searchCursor{Mode: mode, Offset: cursor.Offset + limit})
+		next = base64.RawURLEncoding.EncodeToString(raw)
+	}
+
+	categories := []facetCount{}
+	if err := matched.Select("category AS value, COUNT(*) AS count").
+		Group("category").Order("count DESC").Order("category").
+		Scan(&categories).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Search failed"})
+	}
+	costs := []facetCount{}
+	if err := matched.Select(costBuckets + " AS value, COUNT(*) AS count").
+		Group("value").Order("MIN(cost)").
+		Scan(&costs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Search failed"})
+	}
+
+	response := pageEnvelope(hits, next, total)
+	response["mode"] = mode
+	response["facets"] = fiber.Map{"category": categories, "cost": costs}
+	return c.JSON(response)
+}
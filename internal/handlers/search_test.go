@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"testing"
+)
+
+func TestAnyTerms(t *testing.T) {
+	tests := []struct {
+		q    string
+		want string
+	}{
+		{"amazon gift card", "amazon gift card"},
+		{"amazon -gift", "amazon"},
+		{"amazon or flipkart", "amazon flipkart"},
+		{`"gift card" amazon`, "gift card amazon"},
+		{`amazon -"gift card"`, "amazon"},
+		{"-amazon -gift", ""},
+		{`"unterminated phrase`, "unterminated phrase"},
+	}
+	for _, tt := range tests {
+		if got := anyTerms(tt.q); got != tt.want {
+			t.Errorf("anyTerms(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "Amazon " + highlightStart + "Gift" + highlightStop + " Card", "Amazon <mark>Gift</mark> Card"},
+		{"script in partner text", "<script>alert(1)</script> " + highlightStart + "card" + highlightStop, "&lt;script&gt;alert(1)&lt;/script&gt; <mark>card</mark>"},
+		{"attribute injection", `"><img src=x onerror=alert(1)>`, "&#34;&gt;&lt;img src=x onerror=alert(1)&gt;"},
+		{"ampersand", "AT&T", "AT&amp;T"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightHTML(tt.in); got != tt.want {
+				t.Errorf("highlightHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/ledger"
+	"authapi/internal/models"
+	"authapi/internal/tiers"
+	"log"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// refreshTier re-evaluates the user's tier from activity in the rolling window.
+// With promoteOnly set the user can only move up, which is used for instant
+// promotion after earning; the nightly job also demotes.
+func refreshTier(tx *gorm.DB, user *models.User, promoteOnly bool) error {
+	since := time.Now().Add(-tiers.Window)
+	earned, err := ledger.CreditedSince(tx, user.ID, since, ledger.ReasonEarn)
+	if err != nil {
+		return err
+	}
+	var redemptions int64
+	if err := tx.Model(&models.Transaction{}).
+		Where("type = ? AND user_id = ? AND status = ? AND created_at >= ?", models.TransactionRedemption, user.ID, models.StatusCompleted, since).
+		Count(&redemptions).Error; err != nil {
+		return err
+	}
+	next, changed := tiers.Next(user.Tier, earned, int(redemptions), promoteOnly)
+	if !changed {
+		return nil
+	}
+	if err := tx.Model(user).UpdateColumn("tier", next.Name).Error; err != nil {
+		return err
+	}
+	log.Printf("User %d moved from %s to %s\n", user.ID, user.Tier, next.Name)
+	user.Tier = next.Name
+	return nil
+}
+
+// EvaluateTiers re-evaluates the tier of every verified user
+func EvaluateTiers() {
+	var users []models.User
+	result := db.DB.Where("is_verified = ?", true).FindInBatches(&users, 200, func(tx *gorm.DB, batch int) error {
+		for i := range users {
+			if err := refreshTier(db.DB, &users[i], false); err != nil {
+				log.Println("Tier evaluation failed for user", users[i].ID, ":", err)
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		log.Println("Tier evaluation failed:", result.Error)
+	}
+}
+
+// isValidMinTier accepts an empty tier (open to all) or a known tier name
+func isValidMinTier(name string) bool {
+	return name == "" || tiers.IsValid(name)
+}
+
+// ListTiers retrieves the loyalty tiers and their thresholds
+func ListTiers(c *fiber.Ctx) error {
+	return c.JSON(tiers.Tiers)
+}
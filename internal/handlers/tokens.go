@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"errors"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var errRefreshTokenReused = errors.New("refresh token reused")
+
+// issueTokens creates a new refresh token in the given family and a matching access token
+func issueTokens(tx *gorm.DB, user models.User, familyID string) (string, string, error) {
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return "", "", err
+	}
+	accessToken, err := utils.GenerateToken(user.ID, user.Role, familyID)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// startSession opens a new refresh token family for the user and returns its first token pair
+func startSession(user models.User) (string, string, error) {
+	familyID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	return issueTokens(db.DB, user, familyID)
+}
+
+// revokeFamily revokes every live refresh token in a family
+func revokeFamily(tx *gorm.DB, familyID string) error {
+	return tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RefreshTokenHandler rotates a refresh token and issues a new access token
+func RefreshTokenHandler(c *fiber.Ctx) error {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if input.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Refresh token required"})
+	}
+
+	var stored models.RefreshToken
+	if err := db.DB.Where("token_hash = ?", utils.HashToken(input.RefreshToken)).First(&stored).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+	// A revoked token being presented again means it was stolen or replayed,
+	// so the whole family is killed and the user has to log in again.
+	if stored.RevokedAt != nil {
+		revokeFamily(db.DB, stored.FamilyID)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token reuse detected"})
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token expired"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, stored.UserID).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Account Not found"})
+	}
+
+	var accessToken, refreshToken string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Conditional revoke so two concurrent refreshes cannot both rotate the same token
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", stored.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+		var err error
+		accessToken, refreshToken, err = issueTokens(tx, user, stored.FamilyID)
+		return err
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		revokeFamily(db.DB, stored.FamilyID)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token reuse detected"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not refresh token"})
+	}
+	return c.JSON(fiber.Map{"token": accessToken, "refresh_token": refreshToken, "role": user.Role})
+}
+
+// LogoutHandler revokes the refresh token family the presented token belongs to
+func LogoutHandler(c *fiber.Ctx) error {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if input.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Refresh token required"})
+	}
+	var stored models.RefreshToken
+	if err := db.DB.Where("token_hash = ?", utils.HashToken(input.RefreshToken)).First(&stored).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+	if err := revokeFamily(db.DB, stored.FamilyID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not log out"})
+	}
+	return c.JSON(fiber.Map{"message": "Logged out"})
+}
+
+// revokeUserSessions revokes every live refresh token the user holds, which also
+// invalidates all of their outstanding access tokens
+func revokeUserSessions(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/ledger"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"errors"
+	"log"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dailyTransferLimit caps the points a user can send to others per day
+const dailyTransferLimit = 1000
+
+var errTransferLimit = errors.New("daily transfer limit exceeded")
+
+// TransferPoints moves points from the logged-in user to another verified user
+func TransferPoints(c *fiber.Ctx) error {
+	senderID := uint(c.Locals("user_id").(float64))
+	var input struct {
+		Recipient string `json:"recipient"`
+		Points    int    `json:"points"`
+		Note      string `json:"note"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if input.Recipient == "" || input.Points <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Recipient and a positive points amount required"})
+	}
+
+	// Recipients are looked up by email, or by username when it is unambiguous
+	var recipients []models.User
+	db.DB.Where("is_verified = ? AND (email = ? OR username = ?)", true, input.Recipient, input.Recipient).Limit(2).Find(&recipients)
+	if len(recipients) == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Recipient not found"})
+	}
+	if len(recipients) > 1 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Several users share this username, use their email"})
+	}
+	recipient := recipients[0]
+	if recipient.ID == senderID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot transfer points to yourself"})
+	}
+
+	var sender models.User
+	var t models.Transaction
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock both wallets in ID order so opposing transfers cannot deadlock
+		var locked []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{senderID, recipient.ID}).
+			Order("id").
+			Find(&locked).Error; err != nil {
+			return err
+		}
+		for _, u := range locked {
+			if u.ID == senderID {
+				sender = u
+			}
+		}
+		if sender.ID == 0 || !sender.IsVerified {
+			return errUserNotFound
+		}
+
+		now := time.Now()
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		var sentToday int
+		if err := tx.Model(&models.Transaction{}).
+			Select("COALESCE(SUM(points_used), 0)").
+			Where("type = ? AND user_id = ? AND created_at >= ?", models.TransactionTransfer, senderID, startOfDay).
+			Scan(&sentToday).Error; err != nil {
+			return err
+		}
+		if sentToday+input.Points > dailyTransferLimit {
+			return errTransferLimit
+		}
+
+		t = models.Transaction{
+			Type:           models.TransactionTransfer,
+			UserID:         senderID,
+			CounterpartyID: recipient.ID,
+			Status:         models.StatusCompleted,
+			PointsUsed:     input.Points,
+			Note:           input.Note,
+			CreatedAt:      now,
+		}
+		if err := tx.Create(&t).Error; err != nil {
+			return err
+		}
+		return ledger.Post(tx, ledger.Wallet(senderID), ledger.Wallet(recipient.ID), input.Points, ledger.ReasonTransfer, &t.ID)
+	})
+	switch {
+	case errors.Is(err, errUserNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+	case errors.Is(err, ledger.ErrInsufficientPoints):
+		return c.Status(400).JSON(fiber.Map{"error": "Insuficient Points"})
+	case errors.Is(err, errTransferLimit):
+		return c.Status(400).JSON(fiber.Map{"error": "Daily transfer limit exceeded", "daily_limit": dailyTransferLimit})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not transfer points"})
+	}
+
+	go func(email, from string, points int) {
+		if err := utils.SendTransferEmail(email, from, points); err != nil {
+			log.Println("Could not send transfer email:", err)
+		}
+	}(recipient.Email, sender.Username, input.Points)
+
+	return c.JSON(fiber.Map{"message": "Points transferred", "transaction": t})
+}
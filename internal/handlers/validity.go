@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+var (
+	errRewardInactive   = errors.New("reward is not active")
+	errRewardNotStarted = errors.New("reward campaign has not started")
+	errRewardEnded      = errors.New("reward campaign has ended")
+	errAlreadyRedeemed  = errors.New("reward can only be redeemed once")
+)
+
+// minCouponValidity is how long a coupon stays usable at least, even when the
+// campaign ends sooner, so a redemption just before the end is not worthless
+const minCouponValidity = 7 * 24 * time.Hour
+
+// availableRewards limits a reward query to approved, active rewards inside
+// their campaign window. A zero StartDate or EndDate leaves that side of the
+// window open.
+func availableRewards(query *gorm.DB, now time.Time) *gorm.DB {
+	return query.Where("approval_status = ? AND is_active = ? AND start_date <= ? AND (end_date > ? OR end_date = ?)", models.RewardApproved, true, now, now, time.Time{})
+}
+
+// rewardEnded reports whether the reward's campaign window has closed
+func rewardEnded(reward models.Reward, now time.Time) bool {
+	return !reward.EndDate.IsZero() && !reward.EndDate.After(now)
+}
+
+// checkRewardWindow returns why a reward cannot be redeemed right now, if it
+// cannot. Rewards awaiting approval are treated as if they did not exist.
+func checkRewardWindow(reward models.Reward, now time.Time) error {
+	switch {
+	case reward.ApprovalStatus != models.RewardApproved:
+		return errRewardNotFound
+	case !reward.IsActive:
+		return errRewardInactive
+	case reward.StartDate.After(now):
+		return errRewardNotStarted
+	case rewardEnded(reward, now):
+		return errRewardEnded
+	}
+	return nil
+}
+
+// validRewardWindow rejects windows that end before they start
+func validRewardWindow(reward models.Reward) bool {
+	return reward.StartDate.IsZero() || reward.EndDate.IsZero() || reward.EndDate.After(reward.StartDate)
+}
+
+// couponExpiry is when a coupon of the reward issued at now stops being
+// accepted: the end of its campaign window, but never sooner than
+// minCouponValidity after issue. Rewards without an end date never expire.
+func couponExpiry(reward models.Reward, now time.Time) *time.Time {
+	if reward.EndDate.IsZero() {
+		return nil
+	}
+	expiresAt := reward.EndDate
+	if earliest := now.Add(minCouponValidity); expiresAt.Before(earliest) {
+		expiresAt = earliest
+	}
+	return &expiresAt
+}
+
+// createReward inserts a reward. GORM leaves false out of the INSERT because
+// is_active defaults to true, so a reward created already ended is switched
+// off explicitly.
+func createReward(tx *gorm.DB, reward *models.Reward) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(reward).Error; err != nil {
+			return err
+		}
+		if reward.IsActive {
+			return nil
+		}
+		return tx.Model(reward).UpdateColumn("is_active", false).Error
+	})
+}
+
+// redeemedBy returns a subquery of rewards the user holds a live redemption of
+func redeemedBy(tx *gorm.DB, userID uint) *gorm.DB {
+	return tx.Model(&models.Transaction{}).
+		Select("reward_id").
+		Where("type = ? AND user_id = ? AND status IN ?", models.TransactionRedemption, userID, []string{models.StatusCompleted, models.StatusPending})
+}
+
+// checkAutoExpire stops a second redemption of a reward that expires for the user once redeemed
+func checkAutoExpire(tx *gorm.DB, reward models.Reward, userID uint) error {
+	if !reward.AutoExpireAfterRedemption {
+		return nil
+	}
+	var count int64
+	if err := redeemedBy(tx, userID).Where("reward_id = ?", reward.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errAlreadyRedeemed
+	}
+	return nil
+}
+
+// DeactivateEndedRewards switches off rewards whose campaign has ended and
+// marks their unused coupons as expired
+func DeactivateEndedRewards() {
+	now := time.Now()
+	result := db.DB.Model(&models.Reward{}).
+		Where("is_active = ? AND end_date <> ? AND end_date <= ?", true, time.Time{}, now).
+		Update("is_active", false)
+	if result.Error != nil {
+		log.Println("Reward deactivation failed:", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Deactivated %d ended rewards\n", result.RowsAffected)
+	}
+
+	result = db.DB.Model(&models.Transaction{}).
+		Where("coupon_status = ? AND coupon_expires_at <= ?", models.CouponActive, now).
+		Update("coupon_status", models.CouponExpired)
+	if result.Error != nil {
+		log.Println("Coupon expiry failed:", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Expired %d coupons\n", result.RowsAffected)
+	}
+}
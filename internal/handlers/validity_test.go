@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"authapi/internal/models"
+	"testing"
+	"time"
+)
+
+func TestCouponExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		endDate time.Time
+		want    *time.Time
+	}{
+		{"open ended", time.Time{}, nil},
+		{"campaign ends later", now.Add(30 * 24 * time.Hour), ptrTime(now.Add(30 * 24 * time.Hour))},
+		{"campaign ends tomorrow", now.Add(24 * time.Hour), ptrTime(now.Add(minCouponValidity))},
+		{"campaign ends exactly at minimum", now.Add(minCouponValidity), ptrTime(now.Add(minCouponValidity))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := couponExpiry(models.Reward{EndDate: tt.endDate}, now)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("couponExpiry = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRewardWindow(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	live := models.Reward{ApprovalStatus: models.RewardApproved, IsActive: true}
+	tests := []struct {
+		name string
+		edit func(r *models.Reward)
+		want error
+	}{
+		{"open window", func(r *models.Reward) {}, nil},
+		{"inside window", func(r *models.Reward) { r.StartDate = now.Add(-time.Hour); r.EndDate = now.Add(time.Hour) }, nil},
+		{"awaiting approval", func(r *models.Reward) { r.ApprovalStatus = models.RewardSubmitted }, errRewardNotFound},
+		{"switched off", func(r *models.Reward) { r.IsActive = false }, errRewardInactive},
+		{"not started", func(r *models.Reward) { r.StartDate = now.Add(time.Hour) }, errRewardNotStarted},
+		{"ends now", func(r *models.Reward) { r.EndDate = now }, errRewardEnded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reward := live
+			tt.edit(&reward)
+			if got := checkRewardWindow(reward, now); got != tt.want {
+				t.Errorf("checkRewardWindow = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lowVoucherThreshold is the remaining inventory at which the partner is alerted
+const lowVoucherThreshold = 10
+
+var errNoVouchers = errors.New("no voucher codes left")
+
+// claimVoucher takes the oldest unused voucher code of the reward
+func claimVoucher(tx *gorm.DB, rewardID uint) (models.VoucherCode, error) {
+	var voucher models.VoucherCode
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("reward_id = ? AND assigned_at IS NULL", rewardID).
+		Order("id").
+		First(&voucher).Error
+	if err != nil {
+		return voucher, errNoVouchers
+	}
+	now := time.Now()
+	voucher.AssignedAt = &now
+	return voucher, tx.Model(&voucher).UpdateColumn("assigned_at", now).Error
+}
+
+// syncVoucherStock sets a voucher reward's stock to its unused codes minus
+// the codes already promised to pending redemptions
+func syncVoucherStock(tx *gorm.DB, rewardID uint) error {
+	var available, pending int64
+	if err := tx.Model(&models.VoucherCode{}).Where("reward_id = ? AND assigned_at IS NULL", rewardID).Count(&available).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Transaction{}).Where("reward_id = ? AND status = ?", rewardID, models.StatusPending).Count(&pending).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Reward{}).Where("id = ?", rewardID).UpdateColumn("stock", available-pending).Error
+}
+
+// alertLowVouchers emails the partner when a voucher reward's stock reaches the
+// low-inventory threshold or runs out
+func alertLowVouchers(reward models.Reward, remaining int) {
+	if !reward.ExternalVouchers || (remaining != lowVoucherThreshold && remaining != 0) {
+		return
+	}
+	go func() {
+		var partner models.User
+		if err := db.DB.First(&partner, reward.CreatedByID).Error; err != nil {
+			return
+		}
+		if err := utils.SendLowInventoryEmail(partner.Email, reward.Name, remaining); err != nil {
+			log.Println("Could not send low inventory email:", err)
+		}
+	}()
+}
+
+// parseVoucherCSV reads codes from the first column of a CSV, skipping blanks,
+// duplicates and a "code" header row. Codes are upper-cased like every coupon
+// code so partners can look them up however they are typed.
+func parseVoucherCSV(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	seen := map[string]bool{}
+	var codes []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 0 {
+			continue
+		}
+		code := strings.ToUpper(strings.TrimSpace(record[0]))
+		if code == "" || code == "CODE" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// takenVoucherCodes returns the codes already issued as coupons or stocked by
+// another reward. Coupon codes are unique across all rewards, so those
+// vouchers could never be handed out.
+func takenVoucherCodes(tx *gorm.DB, rewardID uint, codes []string) (map[string]bool, error) {
+	taken := map[string]bool{}
+	for start := 0; start < len(codes); start += 500 {
+		end := start + 500
+		if end > len(codes) {
+			end = len(codes)
+		}
+		var found []string
+		if err := tx.Model(&models.Transaction{}).Where("coupon_code IN ?", codes[start:end]).Pluck("coupon_code", &found).Error; err != nil {
+			return nil, err
+		}
+		var stocked []string
+		if err := tx.Model(&models.VoucherCode{}).Where("reward_id <> ? AND code IN ?", rewardID, codes[start:end]).Pluck("code", &stocked).Error; err != nil {
+			return nil, err
+		}
+		for _, code := range append(found, stocked...) {
+			taken[code] = true
+		}
+	}
+	return taken, nil
+}
+
+// findOwnedReward loads a reward from the :id route param and checks the partner owns it
+func findOwnedReward(c *fiber.Ctx, partnerID uint) (models.Reward, error) {
+	var reward models.Reward
+	rewardID, err := c.ParamsInt("id")
+	if err != nil {
+		return reward, err
+	}
+	if err := db.DB.First(&reward, rewardID).Error; err != nil {
+		return reward, err
+	}
+	if reward.CreatedByID != partnerID {
+		return reward, errNotRewardOwner
+	}
+	return reward, nil
+}
+
+// PartnerUploadVouchers adds partner-supplied voucher codes to a reward from a
+// CSV sent either as the "file" form field or as the raw request body
+func PartnerUploadVouchers(c *fiber.Ctx) error {
+	partnerID := uint(c.Locals("user_id").(float64))
+	reward, err := findOwnedReward(c, partnerID)
+	if errors.Is(err, errNotRewardOwner) {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this reward"})
+	}
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Reward not found"})
+	}
+
+	var source io.Reader = bytes.NewReader(c.Body())
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Could not read file"})
+		}
+		defer f.Close()
+		source = f
+	}
+	codes, err := parseVoucherCSV(source)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid CSV"})
+	}
+	if len(codes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No voucher codes found"})
+	}
+
+	var added, conflicts int64
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, reward.ID).Error; err != nil {
+			return err
+		}
+		taken, err := takenVoucherCodes(tx, reward.ID, codes)
+		if err != nil {
+			return err
+		}
+		var vouchers []models.VoucherCode
+		for _, code := range codes {
+			if taken[code] {
+				conflicts++
+				continue
+			}
+			vouchers = append(vouchers, models.VoucherCode{RewardID: reward.ID, Code: code})
+		}
+		if len(vouchers) == 0 {
+			return nil
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&vouchers, 500)
+		if result.Error != nil {
+			return result.Error
+		}
+		added = result.RowsAffected
+		if err := tx.Model(&reward).UpdateColumn("external_vouchers", true).Error; err != nil {
+			return err
+		}
+		return syncVoucherStock(tx, reward.ID)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not store voucher codes"})
+	}
+	db.DB.First(&reward, reward.ID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Voucher codes uploaded",
+		"added":      added,
+		"duplicates": int64(len(codes)) - added - conflicts,
+		"conflicts":  conflicts,
+		"stock":      reward.Stock,
+	})
+}
+
+// PartnerVoucherInventory summarises the voucher codes of a reward
+func PartnerVoucherInventory(c *fiber.Ctx) error {
+	partnerID := uint(c.Locals("user_id").(float64))
+	reward, err := findOwnedReward(c, partnerID)
+	if errors.Is(err, errNotRewardOwner) {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this reward"})
+	}
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Reward not found"})
+	}
+	var total, available int64
+	db.DB.Model(&models.VoucherCode{}).Where("reward_id = ?", reward.ID).Count(&total)
+	db.DB.Model(&models.VoucherCode{}).Where("reward_id = ? AND assigned_at IS NULL", reward.ID).Count(&available)
+	return c.JSON(fiber.Map{
+		"total":     total,
+		"assigned":  total - available,
+		"available": available,
+		"stock":     reward.Stock,
+		"low":       reward.Stock <= lowVoucherThreshold,
+	})
+}
@@ -0,0 +1,190 @@
+package ledger
+
+import (
+	"authapi/internal/models"
+	"authapi/internal/utils"
+	"errors"
+	"time"
+	"gorm.io/gorm"
+)
+
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// Reason codes recorded on ledger entries
+const (
+	ReasonOpeningBalance = "opening_balance"
+	ReasonSignupBonus    = "signup_bonus"
+	ReasonRedemption     = "redemption"
+	ReasonEarn           = "earn"
+	ReasonExpiry         = "expiry"
+	ReasonTransfer       = "transfer"
+	ReasonReferral       = "referral"
+	ReasonRefund         = "refund"
+	ReasonHold           = "hold"
+	ReasonHoldCapture    = "hold_capture"
+	ReasonHoldRelease    = "hold_release"
+)
+
+// Account identifies one side of a posting. User wallets are keyed by UserID;
+// system accounts have UserID 0.
+type Account struct {
+	Name   string
+	UserID uint
+}
+
+const walletAccount = "wallet"
+
+var (
+	// Issuance is the source of all points credited to wallets
+	Issuance = Account{Name: "issuance"}
+	// Redemptions collects points spent on rewards
+	Redemptions = Account{Name: "redemptions"}
+	// Holds keeps points reserved by pending redemptions
+	Holds = Account{Name: "holds"}
+	// Expired collects points whose lots lapsed before being spent
+	Expired = Account{Name: "expired"}
+)
+
+// Wallet returns the ledger account of a user's points wallet
+func Wallet(userID uint) Account {
+	return Account{Name: walletAccount, UserID: userID}
+}
+
+// Post moves amount points from one account to another as a balanced pair of
+// entries, keeping the users.points cache and the wallet's point lots in step.
+// Debits consume lots soonest-expiry first. Credits open new lots; points moved
+// straight between wallets keep their original expiry, as do refunded points
+// and released holds.
+// A wallet can never be debited below zero. Must be called inside a database
+// transaction.
+func Post(tx *gorm.DB, from, to Account, amount int, reason string, transactionID *uint) error {
+	if amount <= 0 {
+		return errors.New("ledger amount must be positive")
+	}
+	var drawn []portion
+	if from.Name == walletAccount {
+		result := tx.Exec("UPDATE users SET points = points - ? WHERE id = ? AND points >= ?", amount, from.UserID, amount)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientPoints
+		}
+		var err error
+		if drawn, err = consumeLots(tx, from.UserID, amount, transactionID); err != nil {
+			// Points in lapsed lots still count in the cache until the sweep runs
+			if errors.Is(err, errLotsShort) {
+				return ErrInsufficientPoints
+			}
+			return err
+		}
+	}
+	if to.Name == walletAccount {
+		// Refunds and released holds give back points with the expiry they were spent with
+		if (from == Redemptions || from == Holds) && transactionID != nil {
+			var err error
+			if drawn, err = drawsFor(tx, to.UserID, *transactionID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("UPDATE users SET points = points + ? WHERE id = ?", amount, to.UserID).Error; err != nil {
+			return err
+		}
+		if err := creditLots(tx, to.UserID, amount, reason, drawn); err != nil {
+			return err
+		}
+	}
+	return writeEntries(tx, from, to, amount, reason, transactionID)
+}
+
+// Credit issues new points to a user's wallet
+func Credit(tx *gorm.DB, userID uint, amount int, reason string, transactionID *uint) error {
+	return Post(tx, Issuance, Wallet(userID), amount, reason, transactionID)
+}
+
+// Debit spends points from a user's wallet
+func Debit(tx *gorm.DB, userID uint, amount int, reason string, transactionID *uint) error {
+	return Post(tx, Wallet(userID), Redemptions, amount, reason, transactionID)
+}
+
+func writeEntries(tx *gorm.DB, from, to Account, amount int, reason string, transactionID *uint) error {
+	journalID, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return err
+	}
+	entries := []models.LedgerEntry{
+		{JournalID: journalID, Account: from.Name, UserID: from.UserID, Debit: amount, Reason: reason, TransactionID: transactionID},
+		{JournalID: journalID, Account: to.Name, UserID: to.UserID, Credit: amount, Reason: reason, TransactionID: transactionID},
+	}
+	return tx.Create(&entries).Error
+}
+
+// Balance derives a user's wallet balance from their ledger entries
+func Balance(tx *gorm.DB, userID uint) (int, error) {
+	var balance int
+	err := tx.Model(&models.LedgerEntry{}).
+		Select("COALESCE(SUM(credit - debit), 0)").
+		Where("account = ? AND user_id = ?", walletAccount, userID).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// Statement returns a page of a user's wallet entries, newest first, and the total entry count
+func Statement(tx *gorm.DB, userID uint, limit, offset int) ([]models.LedgerEntry, int64, error) {
+	var entries []models.LedgerEntry
+	var total int64
+	query := tx.Model(&models.LedgerEntry{}).Where("account = ? AND user_id = ?", walletAccount, userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id desc").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
+
+// Mismatch describes a user whose cached points disagree with the ledger
+type Mismatch struct {
+	UserID        uint `json:"user_id"`
+	CachedPoints  int  `json:"cached_points"`
+	LedgerBalance int  `json:"ledger_balance"`
+}
+
+// Reconcile compares every user's cached points against their ledger balance
+func Reconcile(tx *gorm.DB) ([]Mismatch, error) {
+	var mismatches []Mismatch
+	err := tx.Raw(`
+		SELECT users.id AS user_id, users.points AS cached_points, COALESCE(SUM(e.credit - e.debit), 0) AS ledger_balance
+		FROM users
+		LEFT JOIN ledger_entries e ON e.account = ? AND e.user_id = users.id
+		WHERE users.deleted_at IS NULL
+		GROUP BY users.id, users.points
+		HAVING users.points <> COALESCE(SUM(e.credit - e.debit), 0)`, walletAccount).
+		Scan(&mismatches).Error
+	return mismatches, err
+}
+
+// Backfill records an opening balance for users whose points predate the
+// ledger, without changing their cached points.
+func Backfill(tx *gorm.DB) error {
+	var users []models.User
+	err := tx.Where("points > 0 AND NOT EXISTS (SELECT 1 FROM ledger_entries e WHERE e.account = ? AND e.user_id = users.id)", walletAccount).
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if err := writeEntries(tx, Issuance, Wallet(u.ID), u.Points, ReasonOpeningBalance, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreditedSince sums the points credited to a user's wallet for the given reasons since a point in time
+func CreditedSince(tx *gorm.DB, userID uint, since time.Time, reasons ...string) (int, error) {
+	var total int
+	err := tx.Model(&models.LedgerEntry{}).
+		Select("COALESCE(SUM(credit), 0)").
+		Where("account = ? AND user_id = ? AND reason IN ? AND created_at >= ?", walletAccount, userID, reasons, since).
+		Scan(&total).Error
+	return total, err
+}
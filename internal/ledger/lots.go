@@ -0,0 +1,189 @@
+package ledger
+
+import (
+	"authapi/internal/models"
+	"errors"
+	"log"
+	"time"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LotLifetime is how long credited points stay spendable
+var LotLifetime = 365 * 24 * time.Hour
+
+var errLotsShort = errors.New("point lots do not cover debit")
+
+// portion is the part of a debit taken from a single lot
+type portion struct {
+	Amount    int
+	ExpiresAt time.Time
+}
+
+func addLot(tx *gorm.DB, userID uint, amount int, reason string, expiresAt time.Time) error {
+	lot := models.PointLot{
+		UserID:    userID,
+		Amount:    amount,
+		Remaining: amount,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+	}
+	return tx.Create(&lot).Error
+}
+
+// creditLots adds amount points to the user's lots. Points moved from another
+// wallet keep the expiry of the lots they were drawn from, so moving them never
+// extends their life; anything not covered by carried starts a fresh lifetime.
+func creditLots(tx *gorm.DB, userID uint, amount int, reason string, carried []portion) error {
+	for _, p := range carried {
+		if amount == 0 {
+			break
+		}
+		take := p.Amount
+		if take > amount {
+			take = amount
+		}
+		if err := addLot(tx, userID, take, reason, p.ExpiresAt); err != nil {
+			return err
+		}
+		amount -= take
+	}
+	if amount == 0 {
+		return nil
+	}
+	return addLot(tx, userID, amount, reason, time.Now().Add(LotLifetime))
+}
+
+// consumeLots takes amount points from the user's live lots, soonest expiry
+// first, and returns what it took from each. Draws made for a transaction are
+// recorded against it. Lots past their expiry are never spent, even before the
+// sweep runs.
+func consumeLots(tx *gorm.DB, userID uint, amount int, transactionID *uint) ([]portion, error) {
+	var lots []models.PointLot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND remaining > 0 AND expired_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("expires_at, id").
+		Find(&lots).Error
+	if err != nil {
+		return nil, err
+	}
+	var taken []portion
+	for _, lot := range lots {
+		if amount == 0 {
+			break
+		}
+		take := lot.Remaining
+		if take > amount {
+			take = amount
+		}
+		if err := tx.Model(&lot).UpdateColumn("remaining", lot.Remaining-take).Error; err != nil {
+			return nil, err
+		}
+		if transactionID != nil {
+			draw := models.PointLotDraw{LotID: lot.ID, UserID: userID, TransactionID: *transactionID, Amount: take, ExpiresAt: lot.ExpiresAt}
+			if err := tx.Create(&draw).Error; err != nil {
+				return nil, err
+			}
+		}
+		taken = append(taken, portion{Amount: take, ExpiresAt: lot.ExpiresAt})
+		amount -= take
+	}
+	if amount > 0 {
+		return nil, errLotsShort
+	}
+	return taken, nil
+}
+
+// drawsFor returns what a transaction took from the user's lots, soonest expiry first
+func drawsFor(tx *gorm.DB, userID, transactionID uint) ([]portion, error) {
+	var drawn []portion
+	err := tx.Model(&models.PointLotDraw{}).
+		Select("amount, expires_at").
+		Where("user_id = ? AND transaction_id = ?", userID, transactionID).
+		Order("expires_at, id").
+		Scan(&drawn).Error
+	return drawn, err
+}
+
+// ExpireLots debits whatever remains of every lapsed lot into the Expired
+// account. Each lot is expired in its own transaction, locking the user before
+// the lot like every other wallet change. A lot that fails is logged and left
+// for the next run; the number of points expired is returned.
+func ExpireLots(db *gorm.DB) (int, error) {
+	var due []models.PointLot
+	if err := db.Where("expires_at < ? AND remaining > 0 AND expired_at IS NULL", time.Now()).Find(&due).Error; err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, d := range due {
+		var expired int
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var user models.User
+			if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, d.UserID).Error; err != nil {
+				return err
+			}
+			var lot models.PointLot
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lot, d.ID).Error; err != nil {
+				return err
+			}
+			if lot.ExpiredAt != nil || lot.Remaining == 0 {
+				return nil
+			}
+			expired = lot.Remaining
+			now := time.Now()
+			if err := tx.Model(&lot).Updates(map[string]interface{}{"remaining": 0, "expired_at": now}).Error; err != nil {
+				return err
+			}
+			result := tx.Exec("UPDATE users SET points = points - ? WHERE id = ? AND points >= ?", expired, lot.UserID, expired)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrInsufficientPoints
+			}
+			return writeEntries(tx, Wallet(lot.UserID), Expired, expired, ReasonExpiry, nil)
+		})
+		if err != nil {
+			log.Printf("Could not expire point lot %d: %v\n", d.ID, err)
+			continue
+		}
+		total += expired
+	}
+	return total, nil
+}
+
+// Expiry is an amount of points due to expire on a given day
+type Expiry struct {
+	Points    int       `json:"points"`
+	ExpiresOn time.Time `json:"expires_on"`
+}
+
+// UpcomingExpiries lists the user's live points grouped by expiry day, soonest
+// first. Lots already past their expiry are left out even before the sweep runs.
+func UpcomingExpiries(tx *gorm.DB, userID uint) ([]Expiry, error) {
+	var expiries []Expiry
+	err := tx.Model(&models.PointLot{}).
+		Select("SUM(remaining) AS points, DATE(expires_at) AS expires_on").
+		Where("user_id = ? AND remaining > 0 AND expired_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Group("DATE(expires_at)").
+		Order("expires_on").
+		Scan(&expiries).Error
+	return expiries, err
+}
+
+// BackfillLots gives users whose points predate lots a single lot covering
+// their balance, starting its lifetime now.
+func BackfillLots(tx *gorm.DB) error {
+	var users []models.User
+	err := tx.Where("points > 0 AND NOT EXISTS (SELECT 1 FROM point_lots l WHERE l.user_id = users.id)").
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if err := addLot(tx, u.ID, u.Points, ReasonOpeningBalance, time.Now().Add(LotLifetime)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
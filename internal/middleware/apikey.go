@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"authapi/internal/db"
+	"authapi/internal/models"
+	"authapi/internal/utils"
+)
+
+// VerifyTokenOrAPIKey authenticates partner requests with either an X-API-Key
+// header or a Bearer JWT. Key-authenticated requests get the "partner_api" role.
+func VerifyTokenOrAPIKey(c *fiber.Ctx) error {
+	key := c.Get("X-API-Key")
+	if key == "" {
+		return VerifyToken(c)
+	}
+
+	var apiKey models.APIKey
+	if err := db.DB.Where("key_hash = ? AND revoked_at IS NULL", utils.HashToken(key)).First(&apiKey).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid API key"})
+	}
+	var owner models.User
+	if err := db.DB.First(&owner, apiKey.UserID).Error; err != nil || owner.Role != "partner" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid API key"})
+	}
+	db.DB.Model(&apiKey).UpdateColumn("last_used_at", time.Now())
+
+	// Stored as float64 to match the JWT claim type handlers expect
+	c.Locals("user_id", float64(apiKey.UserID))
+	c.Locals("role", "partner_api")
+	c.Locals("api_key_id", apiKey.ID)
+	return c.Next()
+}
@@ -4,37 +4,68 @@ import (
 	"strings"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"authapi/internal/db"
+	"authapi/internal/models"
 	"authapi/internal/utils"
 )
 
 func VerifyToken(c *fiber.Ctx) error {
+	if problem := authenticate(c); problem != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": problem})
+	}
+	return c.Next()
+}
+
+// authenticate checks the bearer token and stores its user, role and session
+// in Locals. It returns why the token was refused, or "" when it was accepted.
+func authenticate(c *fiber.Ctx) string {
 	authHeader := c.Get("Authorization")
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token format"})
+		return "Invalid token format"
 	}
 
 	tokenStr := parts[1]
 	token, err := jwt.Parse(tokenStr, utils.ExtractSecretKey)
 	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+		return "Invalid token"
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		return "Invalid token claims"
 	}
 
 	userId, ok := claims["user_id"] 
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token data"})
+		return "Invalid token data"
 	}
 
 	role, ok:= claims["role"]
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token data"})
+		return "Invalid token data"
+	}
+	// Access tokens are tied to a refresh token family so logout takes effect immediately
+	sessionID, ok := claims["sid"].(string)
+	if !ok {
+		return "Invalid token data"
+	}
+	var active int64
+	db.DB.Model(&models.RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", sessionID).Count(&active)
+	if active == 0 {
+		return "Session revoked"
 	}
 	c.Locals("user_id", userId)
 	c.Locals("role", role)
+	c.Locals("session_id", sessionID)
+	return ""
+}
+
+// OptionalToken authenticates the request when a valid token is supplied.
+// Missing, expired or revoked tokens fall back to an anonymous request.
+func OptionalToken(c *fiber.Ctx) error {
+	if c.Get("Authorization") != "" {
+		authenticate(c)
+	}
 	return c.Next()
 }
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+	"authapi/internal/db"
+	"authapi/internal/models"
+)
+
+// IdempotencyWindow is how long a stored response is replayed for
+const IdempotencyWindow = 24 * time.Hour
+
+// Idempotency replays the stored response when a request is retried with the
+// same Idempotency-Key header, and rejects reuse of a key with a different
+// request. Keys are scoped per user, so it must run after authentication.
+func Idempotency(c *fiber.Ctx) error {
+	key := c.Get("Idempotency-Key")
+	if key == "" {
+		return c.Next()
+	}
+	if len(key) > 255 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Idempotency key too long"})
+	}
+	userID := uint(c.Locals("user_id").(float64))
+	sum := sha256.Sum256(append([]byte(c.Method()+" "+c.Path()+"\n"), c.Body()...))
+	requestHash := hex.EncodeToString(sum[:])
+
+	// Drop an expired record so the key can be used again
+	db.DB.Where("user_id = ? AND key = ? AND expires_at < ?", userID, key, time.Now()).Delete(&models.IdempotencyKey{})
+
+	record := models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(IdempotencyWindow),
+	}
+	result := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not store idempotency key"})
+	}
+	if result.RowsAffected == 0 {
+		var existing models.IdempotencyKey
+		if err := db.DB.Where("user_id = ? AND key = ?", userID, key).First(&existing).Error; err != nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Request with this idempotency key is in progress"})
+		}
+		if existing.RequestHash != requestHash {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Idempotency key reused with a different request"})
+		}
+		if existing.StatusCode == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Request with this idempotency key is in progress"})
+		}
+		c.Set("Idempotent-Replayed", "true")
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Status(existing.StatusCode).Send(existing.Response)
+	}
+
+	err := c.Next()
+	status := c.Response().StatusCode()
+	// Failures that were not the client's fault are not stored so the request can be retried
+	if err != nil || status >= fiber.StatusInternalServerError {
+		db.DB.Delete(&record)
+		return err
+	}
+	db.DB.Model(&record).Updates(map[string]interface{}{
+		"status_code": status,
+		"response":    c.Response().Body(),
+	})
+	return nil
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// Throttle limits each client IP to max requests per window. It guards public
+// routes, which have no user to scope an Idempotency-Key to. Counts are kept in
+// memory, per process.
+func Throttle(max int, window time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: window,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many requests, please try again later"})
+		},
+	})
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey lets a partner's backend authenticate without a user session.
+// Only a hash of the key is stored; Prefix is kept so partners can tell keys apart.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `gorm:"uniqueIndex" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
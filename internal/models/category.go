@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Category groups rewards in the catalog. Categories nest through ParentID and
+// siblings are listed by DisplayOrder, then name.
+type Category struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `json:"name"`
+	Slug         string    `gorm:"uniqueIndex" json:"slug"`
+	ParentID     *uint     `gorm:"index" json:"parent_id"`
+	DisplayOrder int       `json:"display_order"`
+	Icon         string    `json:"icon"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// EarnEvent is a qualifying action posted by a partner. ExternalID is the
+// partner's own reference and may only be credited once.
+type EarnEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PartnerID  uint      `gorm:"uniqueIndex:idx_earn_event_external" json:"partner_id"`
+	ExternalID string    `gorm:"uniqueIndex:idx_earn_event_external" json:"external_id"`
+	UserID     uint      `gorm:"index" json:"user_id"`
+	Action     string    `json:"action"`
+	Amount     float64   `json:"amount"`
+	Category   string    `json:"category"`
+	Points     int       `json:"points"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EarnAward records the points one rule contributed to an event, used to
+// enforce per-rule daily caps.
+type EarnAward struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	EventID   uint      `gorm:"index" json:"event_id"`
+	RuleID    uint      `gorm:"index:idx_earn_award_user_rule" json:"rule_id"`
+	UserID    uint      `gorm:"index:idx_earn_award_user_rule" json:"user_id"`
+	Points    int       `json:"points"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// EarnRule configures how many points a qualifying event is worth. Fixed rules
+// award Points per event, percent rules award Percent of the purchase amount and
+// multiplier rules scale whatever the other matching rules award.
+type EarnRule struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `json:"name"`
+	Action     string    `gorm:"index" json:"action"`
+	Type       string    `json:"type"`
+	Points     int       `json:"points"`
+	Percent    float64   `json:"percent"`
+	Multiplier float64   `json:"multiplier"`
+	Category   string    `json:"category"`
+	PartnerID  uint      `json:"partner_id"`
+	DailyCap   int       `json:"daily_cap"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey stores the first response to a mutating request so retries
+// carrying the same Idempotency-Key header are replayed instead of re-executed.
+// A StatusCode of 0 means the original request is still being processed.
+type IdempotencyKey struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"uniqueIndex:idx_idempotency_user_key" json:"user_id"`
+	Key         string    `gorm:"uniqueIndex:idx_idempotency_user_key" json:"key"`
+	RequestHash string    `json:"-"`
+	StatusCode  int       `json:"status_code"`
+	Response    []byte    `json:"-"`
+	ExpiresAt   time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// LedgerEntry is one side of a double-entry posting. Entries are append-only:
+// every posting writes a debit and a matching credit sharing a JournalID, and
+// a wallet balance is the sum of its credits minus its debits.
+type LedgerEntry struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	JournalID     string    `gorm:"index" json:"journal_id"`
+	Account       string    `gorm:"index:idx_ledger_account" json:"account"`
+	UserID        uint      `gorm:"index:idx_ledger_account" json:"user_id"`
+	Credit        int       `json:"credit"`
+	Debit         int       `json:"debit"`
+	Reason        string    `json:"reason"`
+	TransactionID *uint     `gorm:"index" json:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
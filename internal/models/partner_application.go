@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Partner application statuses
+const (
+	ApplicationPending  = "pending"
+	ApplicationApproved = "approved"
+	ApplicationRejected = "rejected"
+)
+
+// PartnerApplication is a business asking to become a partner. Approving it
+// creates the partner account and emails an invite to set its password.
+type PartnerApplication struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	CompanyName      string     `json:"company_name"`
+	ContactName      string     `json:"contact_name"`
+	Email            string     `gorm:"index" json:"email"`
+	Phone            string     `json:"phone"`
+	Website          string     `json:"website"`
+	Address          string     `json:"address"`
+	TaxID            string     `json:"tax_id"`
+	Description      string     `json:"description"`
+	Status           string     `gorm:"default:'pending';index" json:"status"`
+	RejectionReason  string     `json:"rejection_reason,omitempty"`
+	ReviewedByID     uint       `json:"reviewed_by_id,omitempty"`
+	ReviewedAt       *time.Time `json:"reviewed_at,omitempty"`
+	PartnerID        uint       `json:"partner_id,omitempty"`
+	InviteTokenHash  string     `gorm:"index" json:"-"`
+	InviteExpiresAt  *time.Time `json:"invite_expires_at,omitempty"`
+	InviteAcceptedAt *time.Time `json:"invite_accepted_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
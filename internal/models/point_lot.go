@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// PointLot is a dated batch of points credited to a wallet. Debits consume
+// lots oldest-expiry first; whatever remains in a lot at ExpiresAt is expired.
+type PointLot struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	Amount    int        `json:"amount"`
+	Remaining int        `json:"remaining"`
+	Reason    string     `json:"reason"`
+	ExpiresAt time.Time  `gorm:"index" json:"expires_at"`
+	ExpiredAt *time.Time `json:"expired_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// PointLotDraw records the points a transaction took from a lot, so a refund
+// can return them with the expiry they had.
+type PointLotDraw struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	LotID         uint      `gorm:"index" json:"lot_id"`
+	UserID        uint      `gorm:"index" json:"user_id"`
+	TransactionID uint      `gorm:"index" json:"transaction_id"`
+	Amount        int       `json:"amount"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
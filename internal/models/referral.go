@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Referral statuses
+const (
+	ReferralPending  = "pending"
+	ReferralCredited = "credited"
+	ReferralRejected = "rejected"
+)
+
+// Referral links a new account to the user whose code it signed up with.
+// Both sides are credited once the referee verifies their email.
+type Referral struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	ReferrerID   uint       `gorm:"index" json:"referrer_id"`
+	RefereeID    uint       `gorm:"uniqueIndex" json:"referee_id"`
+	Status       string     `gorm:"index" json:"status"`
+	RejectReason string     `json:"reject_reason,omitempty"`
+	SignupIP     string     `gorm:"index" json:"-"`
+	CreditedAt   *time.Time `json:"credited_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
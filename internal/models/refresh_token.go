@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is one link in a rotating refresh token family. Every login
+// starts a new family; every refresh revokes the presented token and adds a
+// new one to the same family.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	FamilyID  string     `gorm:"index" json:"family_id"`
+	TokenHash string     `gorm:"uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -4,10 +4,21 @@ import (
 	"time"
 )
 
+// Approval statuses of a reward. Partner rewards start as drafts and only reach
+// the catalog once an admin approves them.
+const (
+	RewardDraft     = "draft"
+	RewardSubmitted = "submitted"
+	RewardApproved  = "approved"
+	RewardRejected  = "rejected"
+)
+
 type Reward struct {
 	ID                        uint      `gorm:"primaryKey" json:"id"`
 	Name                      string    `gorm:"unique"    json:"name"`
+	// Category mirrors the name of the category CategoryID points to
 	Category                  string    `json:"category"`
+	CategoryID                *uint     `gorm:"index" json:"category_id"`
 	Cost                      int       `json:"cost"`
 	Stock                     int       `json:"stock"`
 	CreatedByID               uint      `json:"created_by_id"`
@@ -16,6 +27,19 @@ type Reward struct {
 	Description               string    `json:"description"`
 	StartDate                 time.Time `json:"start_date"`
 	EndDate                   time.Time `json:"end_date"`
+	// AutoExpireAfterRedemption makes the reward redeemable once per user
 	AutoExpireAfterRedemption bool      `json:"auto_expire_after_redemption"`
+	// IsActive is cleared once the campaign ends and can be toggled by the owner
+	IsActive                  bool      `gorm:"default:true" json:"is_active"`
+	ApprovalStatus            string    `gorm:"default:'approved';index" json:"approval_status"`
+	RejectionReason           string    `json:"rejection_reason,omitempty"`
+	SubmittedAt               *time.Time `json:"submitted_at,omitempty"`
+	ReviewedByID              uint      `json:"reviewed_by_id,omitempty"`
+	ReviewedAt                *time.Time `json:"reviewed_at,omitempty"`
+	MinTier                   string    `gorm:"default:''" json:"min_tier"`
+	// RequiresConfirmation makes redemptions pending until the partner fulfils them
+	RequiresConfirmation      bool      `json:"requires_confirmation"`
+	// ExternalVouchers rewards hand out partner-uploaded codes and derive Stock from them
+	ExternalVouchers          bool      `json:"external_vouchers"`
 }
 
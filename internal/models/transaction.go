@@ -3,12 +3,49 @@ import (
 	"time"
 )
 
+// Transaction types
+const (
+	TransactionRedemption = "redemption"
+	TransactionTransfer   = "transfer"
+)
+
+// Transaction statuses
+const (
+	StatusCompleted = "Completed"
+	StatusRefunded  = "Refunded"
+	// Pending redemptions hold points and stock until the partner confirms or rejects them
+	StatusPending  = "Pending"
+	StatusReleased = "Released"
+)
+
+// Coupon statuses of a redemption
+const (
+	CouponActive   = "active"
+	CouponConsumed = "consumed"
+	CouponVoid     = "void"
+	CouponExpired  = "expired"
+)
+
+// Transaction records a reward redemption or a points transfer. For transfers
+// UserID is the sender and CounterpartyID the recipient.
 type Transaction struct{
 	ID          uint      `gorm:"primaryKey" json:"id"`
+	Type        string    `gorm:"default:'redemption';index" json:"type"`
 	UserID      uint      `json:"user_id"`
+	CounterpartyID uint   `gorm:"index" json:"counterparty_id,omitempty"`
 	RewardID    uint      `json:"reward_id"`
 	Status      string    `json:"status"`
-	CouponCode string    `json:"coupon_code"`
+	CouponCode string    `gorm:"uniqueIndex:idx_transactions_coupon_code,where:coupon_code <> ''" json:"coupon_code"`
+	CouponStatus string  `gorm:"default:'active'" json:"coupon_status"`
 	PointsUsed  int       `json:"points_used"`
+	Note        string    `json:"note,omitempty"`
+	RefundedByID uint     `json:"refunded_by_id,omitempty"`
+	RefundReason string   `json:"refund_reason,omitempty"`
+	RefundedAt  *time.Time `json:"refunded_at,omitempty"`
+	HoldExpiresAt *time.Time `gorm:"index" json:"hold_expires_at,omitempty"`
+	ReleaseReason string   `json:"release_reason,omitempty"`
+	ConsumedAt  *time.Time `json:"consumed_at,omitempty"`
+	ConsumedLocation string `json:"consumed_location,omitempty"`
+	CouponExpiresAt *time.Time `gorm:"index" json:"coupon_expires_at,omitempty"`
 	CreatedAt   time.Time `json:"created_at"` 
-}
\ No newline at end of file
+}
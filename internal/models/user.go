@@ -9,8 +9,13 @@ type User struct {
 	Email    	 string    `gorm:"unique" json:"email"`
 	Password 	 string    `json:"password"`
 	Role     	 string    `json:"role"`
-	Points   	 int       `json:"points"`
+	// Points caches the ledger balance and is only ever written by the ledger package
+	Points   	 int       `gorm:"->;default:0" json:"points"`
 	IsVerified   bool      `json:"is_verified"`
+	Tier         string    `gorm:"default:'Silver'" json:"tier"`
+	ReferralCode string    `gorm:"uniqueIndex" json:"referral_code"`
 	OTP          string    `json:"-"`
 	OTPExpiresAt time.Time `json:"-"`
+	OTPSentAt    time.Time `json:"-"`
+	OTPAttempts  int       `json:"-"`
 }
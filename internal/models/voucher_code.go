@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// VoucherCode is a partner-supplied code handed out in place of a generated
+// coupon. A code is used once AssignedAt is set; the redemption holding it
+// carries the same value in Transaction.CouponCode.
+type VoucherCode struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	RewardID   uint       `gorm:"uniqueIndex:idx_voucher_reward_code" json:"reward_id"`
+	Code       string     `gorm:"uniqueIndex:idx_voucher_reward_code" json:"code"`
+	AssignedAt *time.Time `json:"assigned_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,92 @@
+package tiers
+
+import (
+	"time"
+)
+
+// Window is the rolling period activity is measured over when qualifying for a tier
+const Window = 365 * 24 * time.Hour
+
+// Tier is a loyalty level. A user qualifies by reaching either threshold
+// within the rolling window.
+type Tier struct {
+	Name            string  `json:"name"`
+	Rank            int     `json:"rank"`
+	MinEarnedPoints int     `json:"min_earned_points"`
+	MinRedemptions  int     `json:"min_redemptions"`
+	EarnMultiplier  float64 `json:"earn_multiplier"`
+}
+
+// Tiers lists every tier from lowest to highest. The first tier is the default.
+var Tiers = []Tier{
+	{Name: "Silver", Rank: 0, MinEarnedPoints: 0, MinRedemptions: 0, EarnMultiplier: 1},
+	{Name: "Gold", Rank: 1, MinEarnedPoints: 2000, MinRedemptions: 10, EarnMultiplier: 1.25},
+	{Name: "Platinum", Rank: 2, MinEarnedPoints: 10000, MinRedemptions: 40, EarnMultiplier: 1.5},
+}
+
+// Default returns the tier every user starts in
+func Default() Tier {
+	return Tiers[0]
+}
+
+// ByName looks up a tier, falling back to the default tier for unknown names
+func ByName(name string) Tier {
+	for _, t := range Tiers {
+		if t.Name == name {
+			return t
+		}
+	}
+	return Default()
+}
+
+// IsValid reports whether name is a known tier
+func IsValid(name string) bool {
+	for _, t := range Tiers {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ForActivity returns the highest tier the activity qualifies for
+func ForActivity(earnedPoints, redemptions int) Tier {
+	qualified := Default()
+	for _, t := range Tiers {
+		if earnedPoints >= t.MinEarnedPoints || redemptions >= t.MinRedemptions {
+			qualified = t
+		}
+	}
+	return qualified
+}
+
+// Next decides the tier a user in current moves to given their activity. When
+// promoteOnly is set the user may only move up; it reports false if nothing changes.
+func Next(current string, earnedPoints, redemptions int, promoteOnly bool) (Tier, bool) {
+	next := ForActivity(earnedPoints, redemptions)
+	if next.Name == current || (promoteOnly && next.Rank <= ByName(current).Rank) {
+		return Tier{}, false
+	}
+	return next, true
+}
+
+// Eligible reports whether a user in userTier may access something restricted
+// to minTier. An empty minTier is open to everyone.
+func Eligible(userTier, minTier string) bool {
+	if minTier == "" {
+		return true
+	}
+	return ByName(userTier).Rank >= ByName(minTier).Rank
+}
+
+// EligibleNames lists the tiers at or below userTier
+func EligibleNames(userTier string) []string {
+	rank := ByName(userTier).Rank
+	var names []string
+	for _, t := range Tiers {
+		if t.Rank <= rank {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
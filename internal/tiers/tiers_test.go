@@ -0,0 +1,80 @@
+package tiers
+
+import (
+	"testing"
+)
+
+func TestForActivity(t *testing.T) {
+	tests := []struct {
+		name        string
+		earned      int
+		redemptions int
+		want        string
+	}{
+		{"no activity", 0, 0, "Silver"},
+		{"just below gold", 1999, 9, "Silver"},
+		{"gold by points", 2000, 0, "Gold"},
+		{"gold by redemptions", 0, 10, "Gold"},
+		{"platinum by points", 10000, 0, "Platinum"},
+		{"platinum by redemptions", 500, 40, "Platinum"},
+		{"gold points platinum redemptions", 2500, 45, "Platinum"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ForActivity(tt.earned, tt.redemptions).Name; got != tt.want {
+				t.Errorf("ForActivity(%d, %d) = %s, want %s", tt.earned, tt.redemptions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     string
+		earned      int
+		redemptions int
+		promoteOnly bool
+		want        string
+		wantChanged bool
+	}{
+		{"promote silver to gold", "Silver", 2000, 0, false, "Gold", true},
+		{"promote on event", "Silver", 12000, 0, true, "Platinum", true},
+		{"skip a tier", "Silver", 0, 40, true, "Platinum", true},
+		{"stay in tier", "Gold", 3000, 0, false, "", false},
+		{"demote platinum to gold", "Platinum", 2000, 0, false, "Gold", true},
+		{"demote to silver", "Gold", 100, 1, false, "Silver", true},
+		{"no demotion on event", "Platinum", 100, 0, true, "", false},
+		{"unknown tier promoted", "Bronze", 2000, 0, true, "Gold", true},
+		{"unknown tier settles on default", "Bronze", 0, 0, false, "Silver", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := Next(tt.current, tt.earned, tt.redemptions, tt.promoteOnly)
+			if changed != tt.wantChanged || got.Name != tt.want {
+				t.Errorf("Next(%s) = %q, %v, want %q, %v", tt.current, got.Name, changed, tt.want, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestEligible(t *testing.T) {
+	tests := []struct {
+		userTier string
+		minTier  string
+		want     bool
+	}{
+		{"Silver", "", true},
+		{"Silver", "Gold", false},
+		{"Gold", "Gold", true},
+		{"Platinum", "Gold", true},
+		{"Gold", "Platinum", false},
+		{"", "Silver", true},
+		{"", "Gold", false},
+	}
+	for _, tt := range tests {
+		if got := Eligible(tt.userTier, tt.minTier); got != tt.want {
+			t.Errorf("Eligible(%q, %q) = %v, want %v", tt.userTier, tt.minTier, got, tt.want)
+		}
+	}
+}
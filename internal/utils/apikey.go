@@ -0,0 +1,13 @@
+package utils
+
+const apiKeyPrefix = "rxk_"
+
+// GenerateAPIKey returns a new partner API key and the short prefix used to identify it
+func GenerateAPIKey() (string, string, error) {
+	secret, err := GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	key := apiKeyPrefix + secret
+	return key, key[:len(apiKeyPrefix)+8], nil
+}
@@ -1,15 +1,163 @@
 package utils
 
 import (
+	"crypto/rand"
+	"errors"
 	"fmt"
-	"math/rand"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
 )
 
+// DefaultCouponAlphabet leaves out 0/O and 1/I/L so codes can be read back without confusion
+const DefaultCouponAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// CouponConfig controls the random part of generated coupon codes
+type CouponConfig struct {
+	Length   int
+	Alphabet string
+}
+
+var (
+	couponSettingsOnce sync.Once
+	couponSettings     CouponConfig
+)
+
+// CouponSettings returns the coupon configuration, overridable with
+// COUPON_LENGTH and COUPON_ALPHABET. It is read on first use so values
+// loaded from .env in main are honoured. An invalid alphabet is logged and
+// the default used instead.
+func CouponSettings() CouponConfig {
+	couponSettingsOnce.Do(func() {
+		couponSettings = CouponConfig{Length: 8, Alphabet: DefaultCouponAlphabet}
+		if n, err := strconv.Atoi(os.Getenv("COUPON_LENGTH")); err == nil && n >= 4 {
+			couponSettings.Length = n
+		}
+		if a := os.Getenv("COUPON_ALPHABET"); a != "" {
+			if err := validCouponAlphabet(a); err != nil {
+				log.Printf("Ignoring COUPON_ALPHABET: %v, using the default\n", err)
+			} else {
+				couponSettings.Alphabet = a
+			}
+		}
+	})
+	return couponSettings
+}
+
+// validCouponAlphabet checks that an alphabet has at least ten distinct
+// characters, all upper-case ASCII letters or digits. Codes are upper-cased
+// before checking, and '-' separates the prefix, so nothing else can be read back.
+func validCouponAlphabet(a string) error {
+	if len(a) < 10 {
+		return errors.New("need at least 10 characters")
+	}
+	for i := 0; i < len(a); i++ {
+		if !(a[i] >= 'A' && a[i] <= 'Z' || a[i] >= '0' && a[i] <= '9') {
+			return fmt.Errorf("character %q is not an upper-case letter or digit", a[i])
+		}
+		if strings.IndexByte(a[:i], a[i]) >= 0 {
+			return fmt.Errorf("character %q appears twice", a[i])
+		}
+	}
+	return nil
+}
+
+// GenerateCouponCode returns PREFIX-<random><check>, where the final character
+// is a Luhn mod N check digit over the random part so typos can be detected
 func GenerateCouponCode(prefix string) string{
-	charset := "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	code:=make([]byte,6)
+	settings := CouponSettings()
+	alphabet := settings.Alphabet
+	code:=make([]byte,settings.Length)
+	max := big.NewInt(int64(len(alphabet)))
 	for i:=range(code){
-		code[i]=charset[rand.Intn(len(charset))]
+		n, _ := rand.Int(rand.Reader, max)
+		code[i]=alphabet[n.Int64()]
+	}
+	body := string(code)
+	return fmt.Sprintf("%v-%v%c", prefix, body, couponCheckChar(body, alphabet))
+}
+
+// CouponPrefix builds a coupon prefix from up to three letters or digits of a reward name
+func CouponPrefix(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			if b.Len() == 3 {
+				break
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return "RWX"
+	}
+	return b.String()
+}
+
+// LooksLikeCouponCode reports whether a code has the shape of a generated one:
+// a prefix of up to three letters or digits and a random part of the configured
+// length plus its check digit, all from the coupon alphabet. Partner voucher
+// codes carry no check digit, so only codes of this shape can be called mistyped.
+func LooksLikeCouponCode(code string) bool {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	prefix, body, ok := strings.Cut(code, "-")
+	if !ok || len(prefix) == 0 || len(prefix) > 3 {
+		return false
+	}
+	for j := 0; j < len(prefix); j++ {
+		if !(prefix[j] >= 'A' && prefix[j] <= 'Z' || prefix[j] >= '0' && prefix[j] <= '9') {
+			return false
+		}
+	}
+	settings := CouponSettings()
+	if len(body) != settings.Length+1 {
+		return false
+	}
+	for j := 0; j < len(body); j++ {
+		if strings.IndexByte(settings.Alphabet, body[j]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckCouponCode reports whether a code's check digit matches its random part
+func CheckCouponCode(code string) bool {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	i := strings.LastIndexByte(code, '-')
+	body := code[i+1:]
+	if len(body) < 2 {
+		return false
+	}
+	alphabet := CouponSettings().Alphabet
+	for j := 0; j < len(body); j++ {
+		if strings.IndexByte(alphabet, body[j]) < 0 {
+			return false
+		}
+	}
+	return couponCheckChar(body[:len(body)-1], alphabet) == body[len(body)-1]
+}
+
+// couponCheckChar computes the Luhn mod N check character of s over alphabet.
+// Luhn folds doubled values by adding their digits, which only keeps every
+// character distinct for even alphabet sizes; odd sizes such as the default
+// alphabet take doubled values mod N instead, so any single typo is caught.
+func couponCheckChar(s, alphabet string) byte {
+	n := len(alphabet)
+	factor := 2
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphabet, s[i])
+		factor = 3 - factor
+		if n%2 == 0 {
+			sum += addend/n + addend%n
+		} else {
+			sum += addend % n
+		}
 	}
-	return fmt.Sprintf("%v-%v", prefix,string(code))
-}
\ No newline at end of file
+	return alphabet[(n-sum%n)%n]
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateCouponCodePassesCheck(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code := GenerateCouponCode("AMZ")
+		if !strings.HasPrefix(code, "AMZ-") {
+			t.Fatalf("code %q is missing its prefix", code)
+		}
+		if len(code) != len("AMZ-")+CouponSettings().Length+1 {
+			t.Fatalf("code %q has the wrong length", code)
+		}
+		if !CheckCouponCode(code) {
+			t.Fatalf("generated code %q fails its own check", code)
+		}
+	}
+}
+
+func TestCheckCouponCodeDetectsSubstitution(t *testing.T) {
+	code := GenerateCouponCode("RWX")
+	alphabet := CouponSettings().Alphabet
+	for i := len("RWX-"); i < len(code); i++ {
+		for j := 0; j < len(alphabet); j++ {
+			if alphabet[j] == code[i] {
+				continue
+			}
+			typo := code[:i] + string(alphabet[j]) + code[i+1:]
+			if CheckCouponCode(typo) {
+				t.Errorf("typo %q of %q passed the check", typo, code)
+			}
+		}
+	}
+}
+
+func TestCheckCouponCode(t *testing.T) {
+	body := "ABCDEFGH"
+	valid := "RWX-" + body + string(couponCheckChar(body, CouponSettings().Alphabet))
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"valid", valid, true},
+		{"lower case", strings.ToLower(valid), true},
+		{"surrounding space", "  " + valid + " ", true},
+		{"without prefix", valid[len("RWX-"):], true},
+		{"wrong check digit", valid[:len(valid)-1] + "Z", valid[len(valid)-1] == 'Z'},
+		{"transposed", "RWX-BACDEFGH" + valid[len(valid)-1:], false},
+		{"character outside alphabet", "RWX-ABCDEFG0" + valid[len(valid)-1:], false},
+		{"empty", "", false},
+		{"prefix only", "RWX-", false},
+		{"single character", "RWX-A", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckCouponCode(tt.code); got != tt.want {
+				t.Errorf("CheckCouponCode(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLooksLikeCouponCode(t *testing.T) {
+	body := "ABCDEFGH"
+	valid := "RWX-" + body + string(couponCheckChar(body, CouponSettings().Alphabet))
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"generated", valid, true},
+		{"wrong check digit", valid[:len(valid)-1] + "Z", true},
+		{"lower case", strings.ToLower(valid), true},
+		{"short prefix", "7" + valid[len("RWX"):], true},
+		{"without prefix", valid[len("RWX-"):], false},
+		{"long prefix", "AMAZ" + valid[len("RWX"):], false},
+		{"too short", valid[:len(valid)-1], false},
+		{"too long", valid + "A", false},
+		{"character outside alphabet", "RWX-ABCDEFG0" + valid[len(valid)-1:], false},
+		{"voucher code", "GIFT-2024-XYZ", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LooksLikeCouponCode(tt.code); got != tt.want {
+				t.Errorf("LooksLikeCouponCode(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidCouponAlphabet(t *testing.T) {
+	tests := []struct {
+		name     string
+		alphabet string
+		valid    bool
+	}{
+		{"default", DefaultCouponAlphabet, true},
+		{"digits and letters", "0123456789ABCDEF", true},
+		{"too short", "ABCDEFGHJ", false},
+		{"duplicate character", "ABCDEFGHJKA", false},
+		{"hyphen", "ABCDEFGHJK-", false},
+		{"lower case", "abcdefghjk", false},
+		{"multibyte", "ABCDEFGHJKÄ", false},
+		{"space", "ABCDE FGHJK", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validCouponAlphabet(tt.alphabet); (err == nil) != tt.valid {
+				t.Errorf("validCouponAlphabet(%q) = %v, want valid %v", tt.alphabet, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCouponPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Amazon Gift Card", "AMA"},
+		{"hp", "HP"},
+		{"7-Eleven", "7EL"},
+		{"Café Ôlé", "CAF"},
+		{"!!!", "RWX"},
+		{"", "RWX"},
+	}
+	for _, tt := range tests {
+		if got := CouponPrefix(tt.name); got != tt.want {
+			t.Errorf("CouponPrefix(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -6,6 +6,46 @@ import(
 )
 
 func SendOTPEmail(to, otp string) error {
+	// Email body
+	body := fmt.Sprintf("%s is your RewardX verification OTP. Please do not share it with anyone.\n It will expire in 5 minutes.\n Team RewardX", otp)
+	return sendEmail(to, "Your OTP Code from RewardX", body)
+}
+
+// SendTransferEmail tells a user they have received points from another user
+func SendTransferEmail(to, sender string, points int) error {
+	body := fmt.Sprintf("%s has sent you %d RewardX points. They are already in your wallet.\n Team RewardX", sender, points)
+	return sendEmail(to, "You received RewardX points", body)
+}
+
+// SendLowInventoryEmail warns a partner that a reward is running out of voucher codes
+func SendLowInventoryEmail(to, reward string, remaining int) error {
+	body := fmt.Sprintf("Your reward \"%s\" has %d voucher codes left. Upload more codes to keep it redeemable.\n Team RewardX", reward, remaining)
+	return sendEmail(to, "RewardX voucher inventory running low", body)
+}
+
+// SendRewardReviewEmail tells a partner whether an admin approved or rejected their reward
+func SendRewardReviewEmail(to, reward string, approved bool, reason string) error {
+	if approved {
+		body := fmt.Sprintf("Your reward \"%s\" has been approved and is now listed in the RewardX catalog.\n Team RewardX", reward)
+		return sendEmail(to, "Your RewardX reward was approved", body)
+	}
+	body := fmt.Sprintf("Your reward \"%s\" was not approved.\n Reason: %s\n You can edit it and submit it again.\n Team RewardX", reward, reason)
+	return sendEmail(to, "Your RewardX reward needs changes", body)
+}
+
+// SendPartnerInviteEmail invites an approved partner to set the password of their new account
+func SendPartnerInviteEmail(to, company, link string) error {
+	body := fmt.Sprintf("Welcome to RewardX! The partner application for %s has been approved.\n Set your password to activate your account: %s\n The link expires in 7 days.\n Team RewardX", company, link)
+	return sendEmail(to, "Your RewardX partner account is ready", body)
+}
+
+// SendPartnerRejectedEmail tells an applicant their partner application was declined
+func SendPartnerRejectedEmail(to, company, reason string) error {
+	body := fmt.Sprintf("The partner application for %s was not approved.\n Reason: %s\n Team RewardX", company, reason)
+	return sendEmail(to, "Your RewardX partner application", body)
+}
+
+func sendEmail(to, subject, body string) error {
 	m := gomail.NewMessage()
 
 	// Sender email
@@ -14,10 +54,7 @@ func SendOTPEmail(to, otp string) error {
 	// Set email headers
 	m.SetHeader("From", from)
 	m.SetHeader("To", to)
-	m.SetHeader("Subject", "Your OTP Code from RewardX")
-
-	// Email body
-	body := fmt.Sprintf("%s is your RewardX verification OTP. Please do not share it with anyone.\n It will expire in 5 minutes.\n Team RewardX", otp)
+	m.SetHeader("Subject", subject)
 	m.SetBody("text/plain", body)
 
 	// Mail server config
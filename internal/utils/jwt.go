@@ -1,6 +1,10 @@
 package utils
 
 import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
     "time"
     "github.com/golang-jwt/jwt/v5"
     "os"
@@ -8,15 +12,37 @@ import (
 
 var SecurityKey = []byte(os.Getenv("JWT_SECRET"))
 
-func GenerateToken(id uint, role string) (string, error) {
+const (
+    AccessTokenTTL  = 15 * time.Minute
+    RefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// GenerateToken issues a short-lived access token bound to a refresh token family
+func GenerateToken(id uint, role string, sessionID string) (string, error) {
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
         "user_id": id,
         "role": role,
-        "exp": time.Now().Add(24 * time.Hour).Unix(),
+        "sid": sessionID,
+        "exp": time.Now().Add(AccessTokenTTL).Unix(),
     })
     return token.SignedString(SecurityKey)
 }
 
 func ExtractSecretKey(token *jwt.Token) (interface{}, error) {
     return SecurityKey,nil
-}
\ No newline at end of file
+}
+
+// GenerateRandomToken returns n random bytes encoded as a URL-safe string
+func GenerateRandomToken(n int) (string, error) {
+    b := make([]byte, n)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 of an opaque token for storage at rest
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
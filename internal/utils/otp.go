@@ -1,13 +1,39 @@
 package utils
 
 import(
-	"math/rand"
-	// "time"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
 	"strconv"
+	"time"
+)
+
+const (
+	OTPTTL            = 5 * time.Minute
+	OTPResendCooldown = 60 * time.Second
+	MaxOTPAttempts    = 5
 )
 
 func GenerateOTP() string {
-	// rand.Seed(time.Now().UnixNano())
-    otp := rand.Intn(900000) + 100000 // 6-digit
-    return strconv.Itoa(otp)
-}
\ No newline at end of file
+	// crypto/rand.Reader does not fail on supported platforms as of Go 1.24
+	n, _ := rand.Int(rand.Reader, big.NewInt(900000))
+	otp := int(n.Int64()) + 100000 // 6-digit
+	return strconv.Itoa(otp)
+}
+
+// HashOTP returns a keyed hash of the OTP so codes are never stored in plaintext
+func HashOTP(otp string) string {
+	mac := hmac.New(sha256.New, SecurityKey)
+	mac.Write([]byte(otp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckOTP compares a submitted OTP against its stored hash in constant time
+func CheckOTP(otp, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	return hmac.Equal([]byte(HashOTP(otp)), []byte(hash))
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+// referralAlphabet leaves out characters that are easily confused when read aloud
+const referralAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateReferralCode returns a random 8-character referral code
+func GenerateReferralCode() string {
+	code := make([]byte, 8)
+	max := big.NewInt(int64(len(referralAlphabet)))
+	for i := range code {
+		n, _ := rand.Int(rand.Reader, max)
+		code[i] = referralAlphabet[n.Int64()]
+	}
+	return string(code)
+}
+
+// NormalizeEmail reduces an address to the mailbox that actually receives it,
+// so "John.Doe+promo@gmail.com" and "johndoe@gmail.com" compare equal.
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+	local, _, _ = strings.Cut(local, "+")
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+		domain = "gmail.com"
+	}
+	return local + "@" + domain
+}
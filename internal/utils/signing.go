@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// couponPayloadVersion prefixes signed coupon payloads so the format can evolve
+const couponPayloadVersion = "RX1"
+
+// ErrNoCouponSigningKey means COUPON_SIGNING_SEED is missing or malformed, so coupons cannot be signed
+var ErrNoCouponSigningKey = errors.New("COUPON_SIGNING_SEED must be 32 bytes, base64 encoded")
+
+var (
+	couponKeyOnce sync.Once
+	couponKey     ed25519.PrivateKey
+	couponKeyErr  error
+)
+
+// parseCouponSigningSeed builds the signing key from a base64 seed of exactly 32 bytes
+func parseCouponSigningSeed(encoded string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrNoCouponSigningKey
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// couponSigningKey loads the Ed25519 key used to sign coupon payloads from
+// COUPON_SIGNING_SEED. There is no fallback: a key derived from another secret
+// would let anyone who learns that secret forge coupons.
+func couponSigningKey() (ed25519.PrivateKey, error) {
+	couponKeyOnce.Do(func() {
+		couponKey, couponKeyErr = parseCouponSigningSeed(os.Getenv("COUPON_SIGNING_SEED"))
+		if couponKeyErr != nil {
+			log.Println("Coupon signing disabled:", couponKeyErr)
+		}
+	})
+	return couponKey, couponKeyErr
+}
+
+// CouponPublicKey returns the base64 public key partners use to verify coupon payloads offline
+func CouponPublicKey() (string, error) {
+	key, err := couponSigningKey()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)), nil
+}
+
+// SignCouponPayload returns RX1.<transaction id>.<coupon code>.<signature>
+func SignCouponPayload(transactionID uint, code string) (string, error) {
+	key, err := couponSigningKey()
+	if err != nil {
+		return "", err
+	}
+	message := fmt.Sprintf("%s.%d.%s", couponPayloadVersion, transactionID, code)
+	sig := ed25519.Sign(key, []byte(message))
+	return message + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// VerifyCouponPayload checks a signed payload and returns the transaction ID and coupon code it carries
+func VerifyCouponPayload(payload string) (uint, string, bool) {
+	cut := strings.LastIndexByte(payload, '.')
+	if cut < 0 {
+		return 0, "", false
+	}
+	key, err := couponSigningKey()
+	if err != nil {
+		return 0, "", false
+	}
+	message, encodedSig := payload[:cut], payload[cut+1:]
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !ed25519.Verify(key.Public().(ed25519.PublicKey), []byte(message), sig) {
+		return 0, "", false
+	}
+	parts := strings.SplitN(message, ".", 3)
+	if len(parts) != 3 || parts[0] != couponPayloadVersion {
+		return 0, "", false
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint(id), parts[2], true
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestParseCouponSigningSeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		seed  string
+		valid bool
+	}{
+		{"32 bytes", base64.StdEncoding.EncodeToString(make([]byte, 32)), true},
+		{"empty", "", false},
+		{"too short", base64.StdEncoding.EncodeToString(make([]byte, 16)), false},
+		{"too long", base64.StdEncoding.EncodeToString(make([]byte, 64)), false},
+		{"not base64", strings.Repeat("!", 44), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseCouponSigningSeed(tt.seed); (err == nil) != tt.valid {
+				t.Errorf("parseCouponSigningSeed(%q) = %v, want valid %v", tt.seed, err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Food & Drinks", "food-drinks"},
+		{"  Shopping  ", "shopping"},
+		{"Gift-Cards", "gift-cards"},
+		{"Travel / Hotels", "travel-hotels"},
+		{"--Entertainment--", "entertainment"},
+		{"Top 10 Deals", "top-10-deals"},
+		{"Café Crème", "café-crème"},
+		{"ELECTRONICS", "electronics"},
+		{"!!!", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Slugify(tt.name); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -15,54 +15,122 @@ import (
 func main() {
 	godotenv.Load()
 	db.Connect()
+	handlers.BackfillReferralCodes()
 
 	app := fiber.New()
 
 	app.Use(cors.New(cors.Config{
     AllowOrigins:     "http://localhost:5173", 
     AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-    AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+    AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-API-Key, Idempotency-Key",
     AllowCredentials: true,
 }))
 
 	// Public routes
 	app.Post("/register", handlers.RegisterUser)
 	app.Post("/verifyotp", handlers.VerifyOTP)
+	app.Post("/resendotp", handlers.ResendOTP)
 	app.Post("/login", handlers.LoginHandler)
-	app.Get("/rewards", handlers.ListRewards)
+	app.Post("/refresh", handlers.RefreshTokenHandler)
+	app.Post("/logout", handlers.LogoutHandler)
+	app.Post("/forgotpassword", handlers.ForgotPassword)
+	app.Post("/resetpassword", handlers.ResetPassword)
+	app.Get("/rewards", middleware.OptionalToken, handlers.ListRewards)
+	app.Get("/rewards/search", middleware.OptionalToken, handlers.SearchRewards)
+	app.Get("/tiers", handlers.ListTiers)
+	app.Get("/categories", handlers.ListCategories)
+	app.Post("/partners/apply", middleware.Throttle(5, time.Hour), handlers.ApplyForPartnership)
+	app.Post("/partners/invite/accept", handlers.AcceptPartnerInvite)
 
 	// user apis
 	user := app.Group("/user",middleware.VerifyToken)
 	user.Get("/profile", handlers.ViewProfile)
 	user.Get("/wallet", handlers.GetUserWallet)
-	user.Post("/redeem", handlers.RedeemReward)
+	user.Post("/redeem", middleware.Idempotency, handlers.RedeemReward)
 	user.Get("/transactions", handlers.GetUserTransactions)
+	user.Get("/transactions/:id/qr", handlers.GetTransactionCode)
+	user.Post("/transfer", middleware.Idempotency, handlers.TransferPoints)
 
 	// admin apis 
-	admin := app.Group("/admin", middleware.VerifyToken)
-	admin.Post("/addreward", handlers.AdminAddReward)
-	admin.Post("/addpartner", handlers.AdminAddPartner)
-	admin.Get("/getpartners", handlers.GetAllPartners)
-	admin.Put("/rewards/:id", handlers.AdminUpdateReward)
-	admin.Delete("/rewards/:id", handlers.AdminDeleteReward)
-	admin.Get("/analytics", handlers.GetAdminAnalytics)
+	admin := app.Group("/admin", middleware.VerifyToken, middleware.RequirePermission(middleware.PermAdminAccess))
+	admin.Post("/addreward", middleware.RequirePermission(middleware.PermManageRewards), middleware.Idempotency, handlers.AdminAddReward)
+	admin.Post("/addpartner", middleware.RequirePermission(middleware.PermManagePartners), middleware.Idempotency, handlers.AdminAddPartner)
+	admin.Get("/getpartners", middleware.RequirePermission(middleware.PermViewPartners), handlers.GetAllPartners)
+	admin.Get("/partner-applications", middleware.RequirePermission(middleware.PermViewPartners), handlers.AdminListPartnerApplications)
+	admin.Post("/partner-applications/:id/approve", middleware.RequirePermission(middleware.PermManagePartners), handlers.AdminApprovePartnerApplication)
+	admin.Post("/partner-applications/:id/reject", middleware.RequirePermission(middleware.PermManagePartners), handlers.AdminRejectPartnerApplication)
+	admin.Post("/partner-applications/:id/resend-invite", middleware.RequirePermission(middleware.PermManagePartners), handlers.AdminResendPartnerInvite)
+	admin.Get("/rewards/review", middleware.RequirePermission(middleware.PermManageRewards), handlers.AdminListRewardsForReview)
+	admin.Post("/rewards/:id/approve", middleware.RequirePermission(middleware.PermManageRewards), handlers.AdminApproveReward)
+	admin.Post("/rewards/:id/reject", middleware.RequirePermission(middleware.PermManageRewards), handlers.AdminRejectReward)
+	admin.Put("/rewards/:id", middleware.RequirePermission(middleware.PermManageRewards), handlers.AdminUpdateReward)
+	admin.Delete("/rewards/:id", middleware.RequirePermission(middleware.PermManageRewards), handlers.AdminDeleteReward)
+	admin.Get("/analytics", middleware.RequirePermission(middleware.PermViewAnalytics), handlers.GetAdminAnalytics)
+	admin.Get("/ledger/reconcile", middleware.RequirePermission(middleware.PermViewLedger), handlers.ReconcileLedger)
+	admin.Post("/earnrules", middleware.RequirePermission(middleware.PermManageEarning), handlers.AdminAddEarnRule)
+	admin.Get("/earnrules", middleware.RequirePermission(middleware.PermManageEarning), handlers.AdminListEarnRules)
+	admin.Put("/earnrules/:id", middleware.RequirePermission(middleware.PermManageEarning), handlers.AdminUpdateEarnRule)
+	admin.Delete("/earnrules/:id", middleware.RequirePermission(middleware.PermManageEarning), handlers.AdminDeleteEarnRule)
+	admin.Get("/referrals/top", middleware.RequirePermission(middleware.PermViewReferrals), handlers.GetTopReferrers)
+	admin.Post("/categories", middleware.RequirePermission(middleware.PermManageCategories), handlers.AdminAddCategory)
+	admin.Put("/categories/:id", middleware.RequirePermission(middleware.PermManageCategories), handlers.AdminUpdateCategory)
+	admin.Delete("/categories/:id", middleware.RequirePermission(middleware.PermManageCategories), handlers.AdminDeleteCategory)
+	admin.Post("/transactions/:id/refund", middleware.RequirePermission(middleware.PermRefund), handlers.AdminRefundTransaction)
 
 	// partner apis 
-	partner := app.Group("/partner", middleware.VerifyToken)
-	partner.Post("/addreward", handlers.PartnerAddReward)
+	partner := app.Group("/partner", middleware.VerifyTokenOrAPIKey, middleware.RequirePermission(middleware.PermPartnerAccess))
+	partner.Post("/addreward", middleware.Idempotency, handlers.PartnerAddReward)
 	partner.Get("/rewards", handlers.GetPartnerRewards)
 	partner.Put("/rewards/:id", handlers.PartnerUpdateReward)
 	partner.Delete("/rewards/:id", handlers.PartnerDeleteReward)
+	partner.Post("/rewards/:id/submit", handlers.PartnerSubmitReward)
+	partner.Post("/rewards/:id/vouchers", handlers.PartnerUploadVouchers)
+	partner.Get("/rewards/:id/vouchers", handlers.PartnerVoucherInventory)
 	partner.Get("/analytics", handlers.GetPartnerAnalytics)
-
-	port := os.Getenv("PORT")
-	log.Fatal(app.Listen(":" + port))
+	partner.Post("/events", middleware.Idempotency, handlers.PartnerPostEarnEvent)
+	partner.Post("/transactions/:id/refund", handlers.PartnerRefundTransaction)
+	partner.Post("/transactions/:id/confirm", handlers.PartnerConfirmRedemption)
+	partner.Post("/transactions/:id/reject", handlers.PartnerRejectRedemption)
+	partner.Get("/coupons/signing-key", handlers.GetCouponSigningKey)
+	partner.Post("/coupons/verify", handlers.PartnerVerifyCoupon)
+	partner.Get("/coupons/:code", handlers.PartnerLookupCoupon)
+	partner.Post("/coupons/:code/consume", handlers.PartnerConsumeCoupon)
+	partner.Post("/apikeys", middleware.RequirePermission(middleware.PermManageAPIKeys), handlers.CreateAPIKey)
+	partner.Get("/apikeys", middleware.RequirePermission(middleware.PermManageAPIKeys), handlers.ListAPIKeys)
+	partner.Post("/apikeys/:id/rotate", middleware.RequirePermission(middleware.PermManageAPIKeys), handlers.RotateAPIKey)
+	partner.Delete("/apikeys/:id", middleware.RequirePermission(middleware.PermManageAPIKeys), handlers.RevokeAPIKey)
 
 	// Start background cleanup every 30 minutes
 	go func() {
 		for {
 			time.Sleep(30 * time.Minute)
 			handlers.CleanUpUnverifiedUsers()
+			handlers.CleanUpExpiredRefreshTokens()
+			handlers.CleanUpExpiredIdempotencyKeys()
+			handlers.ExpirePoints()
+			handlers.ReleaseExpiredHolds()
+			handlers.DeactivateEndedRewards()
 		}
 	}()
+
+	// Re-evaluate loyalty tiers nightly at 02:00
+	go func() {
+		for {
+			time.Sleep(untilNext(2))
+			handlers.EvaluateTiers()
+		}
+	}()
+
+	port := os.Getenv("PORT")
+	log.Fatal(app.Listen(":" + port))
+}
+
+// untilNext returns how long to wait until the given hour of the day, local time
+func untilNext(hour int) time.Duration {
+	now := time.Now()
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next.Sub(now)
 }